@@ -0,0 +1,114 @@
+package gocroaring
+
+// Arena owns temporary bitmaps, such as the intermediate results of a query, and frees them
+// all at once when Release is called, instead of leaving them to the garbage collector.
+// Detach keeps a result alive past the release. An Arena is not safe for concurrent use.
+type Arena struct {
+	bitmaps []*Bitmap
+}
+
+// NewArena creates an empty arena
+func NewArena() *Arena {
+	return &Arena{}
+}
+
+// Own registers a bitmap with the arena, so that Release frees it, and returns it
+func (a *Arena) Own(rb *Bitmap) *Bitmap {
+	if rb != nil {
+		a.bitmaps = append(a.bitmaps, rb)
+	}
+	return rb
+}
+
+// Detach removes the bitmap from the arena, so that Release does not free it, and returns it
+func (a *Arena) Detach(rb *Bitmap) *Bitmap {
+	// the detached bitmap is usually one of the last ones created
+	for i := len(a.bitmaps) - 1; i >= 0; i-- {
+		if a.bitmaps[i] == rb {
+			last := len(a.bitmaps) - 1
+			a.bitmaps[i] = a.bitmaps[last]
+			a.bitmaps[last] = nil
+			a.bitmaps = a.bitmaps[:last]
+			break
+		}
+	}
+	return rb
+}
+
+// Release frees all the bitmaps owned by the arena. They must not be used afterwards.
+// The arena is empty after the call and can be reused.
+func (a *Arena) Release() {
+	for i, rb := range a.bitmaps {
+		rb.Free()
+		a.bitmaps[i] = nil
+	}
+	a.bitmaps = a.bitmaps[:0]
+}
+
+// Len returns the number of bitmaps owned by the arena
+func (a *Arena) Len() int {
+	return len(a.bitmaps)
+}
+
+// New creates a bitmap owned by the arena, see New
+func (a *Arena) New(x ...uint32) *Bitmap {
+	return a.Own(New(x...))
+}
+
+// Clone copies a bitmap into a new bitmap owned by the arena
+func (a *Arena) Clone(x ReadOnlyBitmap) *Bitmap {
+	return a.Own(x.Clone())
+}
+
+// Or computes the union between two bitmaps, owned by the arena
+func (a *Arena) Or(x1, x2 ReadOnlyBitmap) *Bitmap {
+	return a.Own(Or(x1, x2))
+}
+
+// And computes the intersection between two bitmaps, owned by the arena
+func (a *Arena) And(x1, x2 ReadOnlyBitmap) *Bitmap {
+	return a.Own(And(x1, x2))
+}
+
+// Xor computes the symmetric difference between two bitmaps, owned by the arena
+func (a *Arena) Xor(x1, x2 ReadOnlyBitmap) *Bitmap {
+	return a.Own(Xor(x1, x2))
+}
+
+// AndNot computes the difference between two bitmaps, owned by the arena
+func (a *Arena) AndNot(x1, x2 ReadOnlyBitmap) *Bitmap {
+	return a.Own(AndNot(x1, x2))
+}
+
+// Flip negates the bits in the given range, the result is owned by the arena
+func (a *Arena) Flip(bm ReadOnlyBitmap, rangeStart, rangeEnd uint64) *Bitmap {
+	return a.Own(Flip(bm, rangeStart, rangeEnd))
+}
+
+// FastOr computes the union between many bitmaps, owned by the arena
+func (a *Arena) FastOr(bitmaps ...*Bitmap) *Bitmap {
+	return a.Own(FastOr(bitmaps...))
+}
+
+// FromIntervals creates a bitmap owned by the arena, see FromIntervals
+func (a *Arena) FromIntervals(intervals []Interval) *Bitmap {
+	return a.Own(FromIntervals(intervals))
+}
+
+// Read reads a serialized bitmap into a bitmap owned by the arena, see Read
+func (a *Arena) Read(b []byte) (*Bitmap, error) {
+	rb, err := Read(b)
+	return a.Own(rb), err
+}
+
+// ReadNative reads a bitmap in the native format into a bitmap owned by the arena, see ReadNative
+func (a *Arena) ReadNative(b []byte) (*Bitmap, error) {
+	rb, err := ReadNative(b)
+	return a.Own(rb), err
+}
+
+// ReadAny reads a bitmap in any format into a bitmap owned by the arena, see ReadAny
+func (a *Arena) ReadAny(b []byte) (*Bitmap, error) {
+	rb, err := ReadAny(b)
+	return a.Own(rb), err
+}
@@ -0,0 +1,49 @@
+package gocroaring
+
+import "testing"
+
+func TestArena(t *testing.T) {
+	x1 := New(1, 2, 3, 1000)
+	x2 := New(2, 3, 4)
+	arena := NewArena()
+	union := arena.Or(x1, x2)
+	intersection := arena.And(x1, x2)
+	difference := arena.AndNot(union, intersection)
+	flipped := arena.Flip(difference, 0, 10)
+	result := arena.Xor(flipped, arena.FastOr(x1, x2, arena.Clone(x1)))
+	buf, err := result.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	read, err := arena.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := arena.Read(nil); err == nil {
+		t.Error("expected an error")
+	}
+	if arena.Len() != 8 {
+		t.Errorf("expected 8 bitmaps, got %d", arena.Len())
+	}
+	if arena.Detach(result) != result {
+		t.Error("bad Detach")
+	}
+	arena.Release()
+	if arena.Len() != 0 {
+		t.Error("the arena was not emptied")
+	}
+	expected := New(0, 1, 4, 5, 6, 7, 8, 9)
+	if !result.Equals(expected) {
+		t.Errorf("bad result %v", result)
+	}
+	expectPanic(t, "Cardinality", func() { read.Cardinality() })
+	expectPanic(t, "Contains", func() { union.Contains(1) })
+	if x1.Cardinality() != 4 || x2.Cardinality() != 3 {
+		t.Error("the operands were freed")
+	}
+	// the arena can be reused
+	arena.Own(result)
+	arena.Release()
+	arena.Release()
+	expectPanic(t, "Equals", func() { result.Equals(expected) })
+}
@@ -50,3 +50,94 @@ func BenchmarkAddOrderedArity(b *testing.B) { benchmarkAddMany(b, ordered) }
 
 func BenchmarkRandomNewFromPtr(b *testing.B)  { benchmarkNewFromPtr(b, random) }
 func BenchmarkOrderedNewFromPtr(b *testing.B) { benchmarkNewFromPtr(b, ordered) }
+
+func benchmarkIterator(b *testing.B, sl []uint32) {
+	rb := gocroaring.New(sl...)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		i := rb.Iterator()
+		for i.HasNext() {
+			i.Next()
+		}
+	}
+}
+
+func benchmarkManyIterator(b *testing.B, sl []uint32) {
+	rb := gocroaring.New(sl...)
+	buf := make([]uint32, 256)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		i := rb.ManyIterator()
+		for i.NextMany(buf) > 0 {
+		}
+	}
+}
+
+func BenchmarkIteratorRandom(b *testing.B)  { benchmarkIterator(b, random) }
+func BenchmarkIteratorOrdered(b *testing.B) { benchmarkIterator(b, ordered) }
+
+func BenchmarkManyIteratorRandom(b *testing.B)  { benchmarkManyIterator(b, random) }
+func BenchmarkManyIteratorOrdered(b *testing.B) { benchmarkManyIterator(b, ordered) }
+
+func benchmarkIterate(b *testing.B, sl []uint32) {
+	rb := gocroaring.New(sl...)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		rb.Iterate(func(x uint32) bool { return true })
+	}
+}
+
+func BenchmarkIterateRandom(b *testing.B)  { benchmarkIterate(b, random) }
+func BenchmarkIterateOrdered(b *testing.B) { benchmarkIterate(b, ordered) }
+
+// spread holds 10000 integers in as many array containers, so that operations on it allocate a lot
+var spread, spreadOther *gocroaring.Bitmap
+
+func init() {
+	spread, spreadOther = gocroaring.New(), gocroaring.New()
+	for i := uint32(0); i < 10000; i++ {
+		spread.Add(i << 16)
+		spreadOther.Add(i<<16 + 1)
+	}
+	spreadOther.Add(5000 << 16)
+}
+
+// benchmarkAllocations runs f, which allocates and frees bitmaps, on one goroutine or on all CPUs
+func benchmarkAllocations(b *testing.B, f func()) {
+	b.Run("serial", func(b *testing.B) {
+		for n := 0; n < b.N; n++ {
+			f()
+		}
+	})
+	b.Run("parallel", func(b *testing.B) {
+		b.RunParallel(func(pb *testing.PB) {
+			for pb.Next() {
+				f()
+			}
+		})
+	})
+}
+
+func BenchmarkOrAllocations(b *testing.B) {
+	benchmarkAllocations(b, func() { gocroaring.Or(spread, spreadOther).Free() })
+}
+
+func BenchmarkAndAllocations(b *testing.B) {
+	benchmarkAllocations(b, func() { gocroaring.And(spread, spreadOther).Free() })
+}
+
+func BenchmarkCloneAllocations(b *testing.B) {
+	benchmarkAllocations(b, func() { spread.Clone().Free() })
+}
+
+func BenchmarkSmallBitmaps(b *testing.B) {
+	benchmarkAllocations(b, func() {
+		var bitmaps [64]*gocroaring.Bitmap
+		for i := range bitmaps {
+			bitmaps[i] = gocroaring.New(uint32(i), uint32(i)<<20)
+		}
+		for _, rb := range bitmaps {
+			rb.Free()
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package gocroaring
+
+import "errors"
+
+// Errors returned by this package, they can be tested with errors.Is.
+var (
+	// ErrNoSuchElement is returned when asking for an integer that is not in the bitmap, e.g. by Select
+	ErrNoSuchElement = errors.New("no such element")
+	// ErrBufferTooSmall is returned when serializing a bitmap into a buffer that cannot hold it
+	ErrBufferTooSmall = errors.New("not enough space")
+	// ErrCorrupt is returned when decoding data that does not hold a valid bitmap
+	ErrCorrupt = errors.New("failed to read roaring array")
+	// ErrEmpty is returned when decoding an empty buffer
+	ErrEmpty = errors.New("empty input")
+	// ErrOutOfMemory is returned by the Try functions when a memory budget would be exceeded,
+	// or when the memory could not be allocated.
+	ErrOutOfMemory = errors.New("out of memory")
+)
@@ -0,0 +1,135 @@
+package gocroaring
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestEmptyInput(t *testing.T) {
+	for _, b := range [][]byte{nil, {}} {
+		if _, err := Read(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("Read: expected ErrEmpty, got %v", err)
+		}
+		if _, err := ReadFrozenView(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("ReadFrozenView: expected ErrEmpty, got %v", err)
+		}
+		if _, err := ReadPortableView(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("ReadPortableView: expected ErrEmpty, got %v", err)
+		}
+		if _, err := ReadNative(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("ReadNative: expected ErrEmpty, got %v", err)
+		}
+		if _, err := ReadAny(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("ReadAny: expected ErrEmpty, got %v", err)
+		}
+		if _, err := Read64(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("Read64: expected ErrEmpty, got %v", err)
+		}
+		if _, err := ReadFrozenView64(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("ReadFrozenView64: expected ErrEmpty, got %v", err)
+		}
+		if err := New().UnmarshalBinary(b); !errors.Is(err, ErrEmpty) {
+			t.Errorf("UnmarshalBinary: expected ErrEmpty, got %v", err)
+		}
+	}
+}
+
+func TestShortInput(t *testing.T) {
+	rb := New(1, 2, 3, 100000)
+	rb.AddRange(1<<20, 1<<21)
+	rb.RunOptimize()
+	buf := make([]byte, rb.SerializedSizeInBytes())
+	rb.Write(buf)
+	rb64 := rb.ToBitmap64(1)
+	buf64 := make([]byte, rb64.SerializedSizeInBytes())
+	rb64.Write(buf64)
+	for n := 1; n < len(buf); n++ {
+		if _, err := Read(buf[:n]); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("Read: expected ErrCorrupt on %d bytes, got %v", n, err)
+		}
+		if _, err := ReadPortableView(buf[:n]); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("ReadPortableView: expected ErrCorrupt on %d bytes, got %v", n, err)
+		}
+		if _, err := ReadAny(buf[:n]); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("ReadAny: expected ErrCorrupt on %d bytes, got %v", n, err)
+		}
+	}
+	for n := 1; n < len(buf64); n++ {
+		if _, err := Read64(buf64[:n]); !errors.Is(err, ErrCorrupt) {
+			t.Fatalf("Read64: expected ErrCorrupt on %d bytes, got %v", n, err)
+		}
+	}
+
+	frozen := AlignedBuffer(rb.FrozenSizeInBytes())
+	rb.WriteFrozen(frozen)
+	for n := 1; n < len(frozen); n++ {
+		for _, mode := range []ViewMode{PinBuffer, CopyBuffer} {
+			if _, err := ReadFrozenViewMode(frozen[:n], mode); !errors.Is(err, ErrCorrupt) {
+				t.Fatalf("ReadFrozenView: expected ErrCorrupt on %d bytes, got %v", n, err)
+			}
+		}
+	}
+	for _, rb64 := range []*Bitmap64{rb64, New64(), New64(1, 1<<40, 1<<50), artBitmap64()} {
+		rb64.ShrinkToFit()
+		frozen64 := AlignedBuffer(rb64.FrozenSizeInBytes())
+		rb64.WriteFrozen(frozen64)
+		for n := 1; n < len(frozen64); n++ {
+			for _, mode := range []ViewMode{PinBuffer, CopyBuffer} {
+				view, err := ReadFrozenView64Mode(frozen64[:n], mode)
+				if err == nil {
+					// only the padding at the end was cut
+					if n < len(frozen64)-63 || !view.Equals(rb64) {
+						t.Fatalf("ReadFrozenView64: expected ErrCorrupt on %d bytes", n)
+					}
+					view.Close()
+				} else if !errors.Is(err, ErrCorrupt) {
+					t.Fatalf("ReadFrozenView64: expected ErrCorrupt on %d bytes, got %v", n, err)
+				}
+			}
+		}
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	rb := New(1, 2, 3)
+	if _, err := rb.Select(3); !errors.Is(err, ErrNoSuchElement) {
+		t.Errorf("Select: expected ErrNoSuchElement, got %v", err)
+	}
+	if _, err := New64(1).Select(1); !errors.Is(err, ErrNoSuchElement) {
+		t.Errorf("Select: expected ErrNoSuchElement, got %v", err)
+	}
+	small := make([]byte, 4)
+	if err := rb.Write(small); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Write: expected ErrBufferTooSmall, got %v", err)
+	}
+	if err := rb.WriteFrozen(small); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("WriteFrozen: expected ErrBufferTooSmall, got %v", err)
+	}
+	if err := rb.WriteNative(small); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("WriteNative: expected ErrBufferTooSmall, got %v", err)
+	}
+	if err := New64(1).Write(small); !errors.Is(err, ErrBufferTooSmall) {
+		t.Errorf("Write: expected ErrBufferTooSmall, got %v", err)
+	}
+}
+
+func TestMinimumMaximumOK(t *testing.T) {
+	if _, ok := New().MinimumOK(); ok {
+		t.Error("MinimumOK on an empty bitmap")
+	}
+	if _, ok := New().MaximumOK(); ok {
+		t.Error("MaximumOK on an empty bitmap")
+	}
+	if x, ok := New(5, 0, 70000).MinimumOK(); !ok || x != 0 {
+		t.Errorf("bad MinimumOK %d %v", x, ok)
+	}
+	if x, ok := New(5, 0, 70000).MaximumOK(); !ok || x != 70000 {
+		t.Errorf("bad MaximumOK %d %v", x, ok)
+	}
+	if _, ok := New64().MinimumOK(); ok {
+		t.Error("MinimumOK on an empty bitmap")
+	}
+	if x, ok := New64(5, 1<<40).MaximumOK(); !ok || x != 1<<40 {
+		t.Errorf("bad MaximumOK %d %v", x, ok)
+	}
+}
@@ -0,0 +1,51 @@
+package gocroaring
+
+/*
+#include <string.h>
+#include "roaring.h"
+*/
+import "C"
+import "unsafe"
+
+// ViewMode tells where a view reads the serialized bitmap from
+type ViewMode int
+
+const (
+	// PinBuffer reads the Go buffer in place, pinned so that the C view may point into it.
+	// The buffer is copied instead if it is not aligned as the format requires, or if it
+	// cannot be pinned (before Go 1.21).
+	PinBuffer ViewMode = iota
+	// CopyBuffer copies the buffer into aligned C memory, which is freed along with the view
+	CopyBuffer
+)
+
+// viewAlignment suits both the 32-bit frozen format (32 bytes) and the 64-bit one (64 bytes)
+const viewAlignment = 64
+
+// AlignedBuffer returns a zeroed slice of length n starting on a 64-byte boundary. Frozen bitmaps
+// written to it with WriteFrozen can be read back in place by ReadFrozenView or ReadFrozenView64.
+func AlignedBuffer(n int) []byte {
+	buf := make([]byte, n+viewAlignment-1)
+	offset := (viewAlignment - int(uintptr(unsafe.Pointer(&buf[0]))%viewAlignment)) % viewAlignment
+	return buf[offset : offset+n]
+}
+
+// viewMemory returns the memory a view should read b from, given the alignment the format requires.
+// It also returns the Go buffer the view must keep alive, and the function releasing the memory
+// once the view is freed.
+func viewMemory(b []byte, alignment uintptr, mode ViewMode) (*C.char, *byte, func() error) {
+	if mode == PinBuffer && uintptr(unsafe.Pointer(&b[0]))%alignment == 0 {
+		if unpin, ok := pinBuffer(&b[0]); ok {
+			return (*C.char)(unsafe.Pointer(&b[0])), &b[0], unpin
+		}
+	}
+	p := C.roaring_aligned_malloc(viewAlignment, C.size_t(len(b)))
+	if p == nil {
+		panic("C code returned a null pointer.")
+	}
+	C.memcpy(p, unsafe.Pointer(&b[0]), C.size_t(len(b)))
+	return (*C.char)(p), nil, func() error {
+		C.roaring_aligned_free(p)
+		return nil
+	}
+}
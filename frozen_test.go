@@ -0,0 +1,89 @@
+package gocroaring
+
+import (
+	"testing"
+	"unsafe"
+)
+
+func TestAlignedBuffer(t *testing.T) {
+	for _, n := range []int{0, 1, 100, 4097} {
+		buf := AlignedBuffer(n)
+		if len(buf) != n {
+			t.Errorf("expected %d bytes, got %d", n, len(buf))
+		}
+		if n > 0 && uintptr(unsafe.Pointer(&buf[0]))%64 != 0 {
+			t.Error("the buffer is not aligned")
+		}
+	}
+}
+
+func TestViewModes(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		frozen := AlignedBuffer(rb.FrozenSizeInBytes() + 1)
+		portable := rb.AppendTo(nil)
+		for _, misaligned := range []bool{false, true} {
+			buf := frozen[:len(frozen)-1]
+			if misaligned {
+				buf = frozen[1:]
+			}
+			rb.WriteFrozen(buf)
+			for _, mode := range []ViewMode{PinBuffer, CopyBuffer} {
+				view, err := ReadFrozenViewMode(buf, mode)
+				if err != nil {
+					t.Fatalf("mode %d, misaligned %v: %v", mode, misaligned, err)
+				}
+				if !view.Equals(rb) {
+					t.Errorf("mode %d, misaligned %v: bad frozen view", mode, misaligned)
+				}
+				view.Close()
+			}
+		}
+
+		copied, err := ReadPortableViewMode(portable, CopyBuffer)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := range portable {
+			portable[i] = 0
+		}
+		if !copied.Equals(rb) {
+			t.Error("the copied view depends on the buffer")
+		}
+		copied.Close()
+	}
+
+	rb := New64(1, 2, 1<<40, 1<<63)
+	buf := AlignedBuffer(rb.FrozenSizeInBytes())
+	rb.WriteFrozen(buf)
+	view, err := ReadFrozenView64Mode(buf, CopyBuffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range buf {
+		buf[i] = 0
+	}
+	if !view.Equals(rb) {
+		t.Error("the copied view depends on the buffer")
+	}
+	view.Close()
+}
+
+func TestCopiedViewMemory(t *testing.T) {
+	EnableMemoryAccounting()
+	rb := bitsetBitmap(16)
+	buf := AlignedBuffer(rb.FrozenSizeInBytes())
+	rb.WriteFrozen(buf)
+	collectBitmaps()
+	before := MemoryStats().LiveBytes
+	view, err := ReadFrozenViewMode(buf, CopyBuffer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if during := MemoryStats().LiveBytes; during < before+uint64(len(buf)) {
+		t.Errorf("expected the buffer to be copied into C memory, got %d then %d", before, during)
+	}
+	view.Close()
+	if after := MemoryStats().LiveBytes; after > before {
+		t.Errorf("expected the copy to be freed, got %d then %d", before, after)
+	}
+}
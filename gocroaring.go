@@ -7,11 +7,60 @@ package gocroaring
 #cgo CFLAGS: -O3  -std=c11
 #include "roaring.h"
 
+// gocroaring_iterator_has_previous returns true if there is a value before the one the iterator points at.
+static bool gocroaring_iterator_has_previous(const roaring_uint32_iterator_t *it) {
+	roaring_uint32_iterator_t copy = *it;
+	return roaring_uint32_iterator_previous(&copy);
+}
+
+// gocroaring_iterator_previous moves the iterator back by one value. When there is
+// no previous value, it stays on the first value and returns false.
+static bool gocroaring_iterator_previous(roaring_uint32_iterator_t *it) {
+	if (roaring_uint32_iterator_previous(it)) {
+		return true;
+	}
+	roaring_uint32_iterator_advance(it);
+	return false;
+}
+
+// gocroaring_iterator_skip_backward is roaring_uint32_iterator_skip_backward, except that it
+// also works from past the last value and never leaves the iterator before the first value.
+static uint32_t gocroaring_iterator_skip_backward(roaring_uint32_iterator_t *it, uint32_t count) {
+	uint32_t skipped = 0;
+	if (count == 0) {
+		return 0;
+	}
+	if (!it->has_value) {
+		// we are past the last value
+		if (!gocroaring_iterator_previous(it)) {
+			return 0;
+		}
+		skipped++;
+		count--;
+	}
+	skipped += roaring_uint32_iterator_skip_backward(it, count);
+	if (!it->has_value) {
+		// moving before the first value counts as a skip, undo it
+		roaring_uint32_iterator_advance(it);
+		skipped--;
+	}
+	return skipped;
+}
+
+// gocroaring_iterator_read_backward is roaring_uint32_iterator_read for an iterator going in decreasing order.
+static uint32_t gocroaring_iterator_read_backward(roaring_uint32_iterator_t *it, uint32_t *buf, uint32_t count) {
+	uint32_t answer = 0;
+	while (it->has_value && answer < count) {
+		buf[answer++] = it->current_value;
+		roaring_uint32_iterator_previous(it);
+	}
+	return answer;
+}
+
 */
 import "C"
 import (
 	"bytes"
-	"errors"
 	"fmt"
 	"runtime"
 	"strconv"
@@ -23,17 +72,53 @@ const CRoaringMinor = C.ROARING_VERSION_MINOR
 const CRoaringRevision = C.ROARING_VERSION_REVISION
 
 func free(a *Bitmap) {
+	untrack(unsafe.Pointer(a.cpointer), true)
 	C.roaring_bitmap_free(a.cpointer)
+	a.cpointer = nil
 }
 
-// Bitmap is the roaring bitmap
+// setFinalizer makes sure the C bitmap is freed along with b
+func setFinalizer(b *Bitmap) {
+	track(unsafe.Pointer(b.cpointer), kindBitmap)
+	runtime.SetFinalizer(b, free)
+}
+
+// bitmapOwner carries the finalizer of a Bitmap that may not be an allocation of its own,
+// such as a struct field filled by json.Unmarshal: a finalizer cannot be set on such a Bitmap.
+type bitmapOwner struct {
+	cpointer *C.struct_roaring_bitmap_s
+}
+
+func freeOwner(o *bitmapOwner) {
+	untrack(unsafe.Pointer(o.cpointer), true)
+	C.roaring_bitmap_free(o.cpointer)
+	o.cpointer = nil
+}
+
+// disown clears the finalizer that would free the C bitmap of rb
+func (rb *Bitmap) disown() {
+	if rb.owner != nil {
+		runtime.SetFinalizer(rb.owner, nil)
+		rb.owner = nil
+		return
+	}
+	runtime.SetFinalizer(rb, nil)
+}
+
+// Bitmap is the roaring bitmap.
+// Its encoding methods, e.g. MarshalJSON, have pointer receivers: declare struct fields as *Bitmap,
+// a field of type Bitmap is encoded by encoding/json as {}.
 type Bitmap struct {
 	cpointer *C.struct_roaring_bitmap_s
+	owner    *bitmapOwner // set when the zero Bitmap was filled in place, e.g. by UnmarshalBinary
+	pooled   bool         // idle in a Pool
 }
 
-type frozenBitmap struct {
-	Bitmap
-	buffer *byte
+// check panics, naming the operation, if the bitmap was freed
+func (rb *Bitmap) check(op string) {
+	if rb.cpointer == nil {
+		panic("gocroaring: " + op + " called on a freed bitmap")
+	}
 }
 
 // New creates a new Bitmap with any number of initial values.
@@ -42,26 +127,33 @@ func New(x ...uint32) *Bitmap {
 	var answer *Bitmap
 	if len(x) > 0 {
 		ptr := unsafe.Pointer(&x[0])
-		answer = &Bitmap{C.roaring_bitmap_of_ptr(C.size_t(len(x)), (*C.uint32_t)(ptr))}
+		answer = &Bitmap{cpointer: C.roaring_bitmap_of_ptr(C.size_t(len(x)), (*C.uint32_t)(ptr))}
 		runtime.KeepAlive(x)
 	} else {
-		answer = &Bitmap{C.roaring_bitmap_create()}
+		answer = &Bitmap{cpointer: C.roaring_bitmap_create()}
 	}
 	if answer.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(answer, free)
+	setFinalizer(answer)
 	return answer
 }
 
+// Free releases the memory held by the bitmap right away, instead of waiting for the garbage collector.
+// The bitmap must not be used afterwards: its methods panic. Calling Free again does nothing.
 func (rb *Bitmap) Free() {
+	if rb.cpointer == nil {
+		return
+	}
 	// Clear the finalizer to avoid double frees
-	runtime.SetFinalizer(rb, nil)
+	rb.disown()
+	untrack(unsafe.Pointer(rb.cpointer), false)
 	free(rb)
 }
 
 // Printf writes a description of the bitmap to stdout
 func (rb *Bitmap) Printf() {
+	rb.check("Printf")
 	fmt.Print("{")
 	i := rb.Iterator()
 	counter := 30
@@ -80,6 +172,7 @@ func (rb *Bitmap) Printf() {
 
 // Add the integer(s) x to the bitmap
 func (rb *Bitmap) Add(x ...uint32) {
+	rb.check("Add")
 	if len(x) == 1 {
 		C.roaring_bitmap_add(rb.cpointer, C.uint32_t(x[0]))
 	} else {
@@ -92,18 +185,21 @@ func (rb *Bitmap) Add(x ...uint32) {
 
 // AddRange - add all values in range [min, max)
 func (rb *Bitmap) AddRange(min, max uint64) {
+	rb.check("AddRange")
 	C.roaring_bitmap_add_range(rb.cpointer, C.uint64_t(min), C.uint64_t(max))
 	runtime.KeepAlive(rb)
 }
 
 // RemoveRange - remove all values in range [min, max)
 func (rb *Bitmap) RemoveRange(min, max uint64) {
+	rb.check("RemoveRange")
 	C.roaring_bitmap_remove_range(rb.cpointer, C.uint64_t(min), C.uint64_t(max))
 	runtime.KeepAlive(rb)
 }
 
 // RunOptimize the compression of the bitmap (call this after populating a new bitmap), return true if the bitmap was modified
 func (rb *Bitmap) RunOptimize() bool {
+	rb.check("RunOptimize")
 	answer := bool(C.roaring_bitmap_run_optimize(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -111,6 +207,7 @@ func (rb *Bitmap) RunOptimize() bool {
 
 // RemoveRunCompression  Remove run-length encoding even when it is more space efficient return whether a change was applied
 func (rb *Bitmap) RemoveRunCompression() bool {
+	rb.check("RemoveRunCompression")
 	answer := bool(C.roaring_bitmap_remove_run_compression(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -121,22 +218,26 @@ func (rb *Bitmap) RemoveRunCompression() bool {
 // This function may panic if the allocation failed.
 func FastOr(bitmaps ...*Bitmap) *Bitmap {
 	number := len(bitmaps)
+	if number == 0 {
+		return New()
+	}
 	po := make([]*C.struct_roaring_bitmap_s, number)
 	for i, v := range bitmaps {
-		po[i] = v.cpointer
+		po[i] = v.cbitmap("FastOr")
 	}
-	b := &Bitmap{C.roaring_bitmap_or_many(C.size_t(number), (**C.struct_roaring_bitmap_s)(unsafe.Pointer(&po[0])))}
+	b := &Bitmap{cpointer: C.roaring_bitmap_or_many(C.size_t(number), (**C.struct_roaring_bitmap_s)(unsafe.Pointer(&po[0])))}
 	runtime.KeepAlive(bitmaps)
 	if b.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(b, free)
+	setFinalizer(b)
 	runtime.KeepAlive(po)
 	return b
 }
 
 // Contains returns true if the integer is contained in the bitmap
 func (rb *Bitmap) Contains(x uint32) bool {
+	rb.check("Contains")
 	answer := bool(C.roaring_bitmap_contains(rb.cpointer, C.uint32_t(x)))
 	runtime.KeepAlive(rb)
 	return answer
@@ -144,6 +245,7 @@ func (rb *Bitmap) Contains(x uint32) bool {
 
 // ContainsRange returns true if the integers in the range [x, y) are contained in the bitmap
 func (rb *Bitmap) ContainsRange(x, y uint64) bool {
+	rb.check("ContainsRange")
 	answer := bool(C.roaring_bitmap_contains_range(rb.cpointer, C.uint64_t(x), C.uint64_t(y)))
 	runtime.KeepAlive(rb)
 	return answer
@@ -151,18 +253,21 @@ func (rb *Bitmap) ContainsRange(x, y uint64) bool {
 
 // Clear removes all elements from the bitmap
 func (rb *Bitmap) Clear() {
+	rb.check("Clear")
 	C.roaring_bitmap_clear(rb.cpointer)
 	runtime.KeepAlive(rb)
 }
 
 // Remove the integer x from the bitmap
 func (rb *Bitmap) Remove(x uint32) {
+	rb.check("Remove")
 	C.roaring_bitmap_remove(rb.cpointer, C.uint32_t(x))
 	runtime.KeepAlive(rb)
 }
 
 // Cardinality returns the number of integers contained in the bitmap
 func (rb *Bitmap) Cardinality() uint64 {
+	rb.check("Cardinality")
 	answer := uint64(C.roaring_bitmap_get_cardinality(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -170,6 +275,7 @@ func (rb *Bitmap) Cardinality() uint64 {
 
 // Cardinality returns the number of integers contained in the bitmap
 func (rb *Bitmap) GetCardinality() uint64 {
+	rb.check("GetCardinality")
 	answer := uint64(C.roaring_bitmap_get_cardinality(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -177,6 +283,7 @@ func (rb *Bitmap) GetCardinality() uint64 {
 
 // Maximum returns the largest of the integers contained in the bitmap assuming that it is not empty
 func (rb *Bitmap) Maximum() uint32 {
+	rb.check("Maximum")
 	answer := uint32(C.roaring_bitmap_maximum(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -184,13 +291,33 @@ func (rb *Bitmap) Maximum() uint32 {
 
 // Minimum returns the smallest of the integers contained in the bitmap assuming that it is not empty
 func (rb *Bitmap) Minimum() uint32 {
+	rb.check("Minimum")
 	answer := uint32(C.roaring_bitmap_minimum(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
 }
 
+// MaximumOK returns the largest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (rb *Bitmap) MaximumOK() (x uint32, ok bool) {
+	rb.check("MaximumOK")
+	if rb.IsEmpty() {
+		return 0, false
+	}
+	return rb.Maximum(), true
+}
+
+// MinimumOK returns the smallest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (rb *Bitmap) MinimumOK() (x uint32, ok bool) {
+	rb.check("MinimumOK")
+	if rb.IsEmpty() {
+		return 0, false
+	}
+	return rb.Minimum(), true
+}
+
 // Rank returns the number of values smaller or equal to x
 func (rb *Bitmap) Rank(x uint32) uint64 {
+	rb.check("Rank")
 	answer := uint64(C.roaring_bitmap_rank(rb.cpointer, C.uint32_t(x)))
 	runtime.KeepAlive(rb)
 	return answer
@@ -198,18 +325,20 @@ func (rb *Bitmap) Rank(x uint32) uint64 {
 
 // Select returns the element having the designated rank, if it exists
 func (rb *Bitmap) Select(rank uint32) (uint32, error) {
+	rb.check("Select")
 	var element uint32 = 0
 	exists := bool(C.roaring_bitmap_select(rb.cpointer, C.uint32_t(rank), (*C.uint32_t)(unsafe.Pointer(&element))))
 	runtime.KeepAlive(rb)
 	if exists {
 		return element, nil
 	} else {
-		return element, errors.New("no such element")
+		return element, ErrNoSuchElement
 	}
 }
 
 // IsEmpty returns true if the Bitmap is empty (it is faster than doing (Cardinality() == 0))
 func (rb *Bitmap) IsEmpty() bool {
+	rb.check("IsEmpty")
 	answer := bool(C.roaring_bitmap_is_empty(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -217,9 +346,10 @@ func (rb *Bitmap) IsEmpty() bool {
 
 // Equals returns true if the two bitmaps contain the same integers
 func (rb *Bitmap) Equals(o interface{}) bool {
-	srb, ok := o.(*Bitmap)
+	rb.check("Equals")
+	srb, ok := o.(ReadOnlyBitmap)
 	if ok {
-		answer := bool(C.roaring_bitmap_equals(rb.cpointer, srb.cpointer))
+		answer := bool(C.roaring_bitmap_equals(rb.cpointer, srb.cbitmap("Equals")))
 		runtime.KeepAlive(rb)
 		runtime.KeepAlive(srb)
 		return answer
@@ -230,93 +360,106 @@ func (rb *Bitmap) Equals(o interface{}) bool {
 // Clone creates a copy of the Bitmap
 // This function may panic if the allocation failed.
 func (rb *Bitmap) Clone() *Bitmap {
-	b := &Bitmap{C.roaring_bitmap_copy(rb.cpointer)}
+	rb.check("Clone")
+	b := &Bitmap{cpointer: C.roaring_bitmap_copy(rb.cpointer)}
 	runtime.KeepAlive(rb)
 	if b.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(b, free)
+	setFinalizer(b)
 	return b
 }
 
 // Assign let rb = x2
-func (rb *Bitmap) Assign(x2 *Bitmap) bool {
-	answer := bool(C.roaring_bitmap_overwrite(rb.cpointer, x2.cpointer))
+func (rb *Bitmap) Assign(x2 ReadOnlyBitmap) bool {
+	rb.check("Assign")
+	answer := bool(C.roaring_bitmap_overwrite(rb.cpointer, x2.cbitmap("Assign")))
 	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
 	return answer
 }
 
 // And computes the intersection between two bitmaps and stores the result in the current bitmap
-func (rb *Bitmap) And(x2 *Bitmap) {
-	C.roaring_bitmap_and_inplace(rb.cpointer, x2.cpointer)
+func (rb *Bitmap) And(x2 ReadOnlyBitmap) {
+	rb.check("And")
+	C.roaring_bitmap_and_inplace(rb.cpointer, x2.cbitmap("And"))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 }
 
 // Xor computes the symmetric difference between two bitmaps and stores the result in the current bitmap
-func (rb *Bitmap) Xor(x2 *Bitmap) {
-	C.roaring_bitmap_xor_inplace(rb.cpointer, x2.cpointer)
+func (rb *Bitmap) Xor(x2 ReadOnlyBitmap) {
+	rb.check("Xor")
+	C.roaring_bitmap_xor_inplace(rb.cpointer, x2.cbitmap("Xor"))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 }
 
 // Or computes the union between two bitmaps and stores the result in the current bitmap
-func (rb *Bitmap) Or(x2 *Bitmap) {
-	C.roaring_bitmap_or_inplace(rb.cpointer, x2.cpointer)
+func (rb *Bitmap) Or(x2 ReadOnlyBitmap) {
+	rb.check("Or")
+	C.roaring_bitmap_or_inplace(rb.cpointer, x2.cbitmap("Or"))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 }
 
 // AndNot computes the difference between two bitmaps and stores the result in the current bitmap
-func (rb *Bitmap) AndNot(x2 *Bitmap) {
-	C.roaring_bitmap_andnot_inplace(rb.cpointer, x2.cpointer)
+func (rb *Bitmap) AndNot(x2 ReadOnlyBitmap) {
+	rb.check("AndNot")
+	C.roaring_bitmap_andnot_inplace(rb.cpointer, x2.cbitmap("AndNot"))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 }
 
 // Intersect checks whether the two bitmaps intersect
-func (rb *Bitmap) Intersect(x2 *Bitmap) bool {
-	answer := bool(C.roaring_bitmap_intersect(rb.cpointer, x2.cpointer))
+func (rb *Bitmap) Intersect(x2 ReadOnlyBitmap) bool {
+	rb.check("Intersect")
+	answer := bool(C.roaring_bitmap_intersect(rb.cpointer, x2.cbitmap("Intersect")))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 	return answer
 }
 
 // JaccardIndex computes the Jaccard index between two bitmaps
-func (rb *Bitmap) JaccardIndex(x2 *Bitmap) float64 {
-	answer := float64(C.roaring_bitmap_jaccard_index(rb.cpointer, x2.cpointer))
+func (rb *Bitmap) JaccardIndex(x2 ReadOnlyBitmap) float64 {
+	rb.check("JaccardIndex")
+	answer := float64(C.roaring_bitmap_jaccard_index(rb.cpointer, x2.cbitmap("JaccardIndex")))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 	return answer
 }
 
 // AndCardinality computes the size of the intersection between two bitmaps
-func (rb *Bitmap) AndCardinality(x2 *Bitmap) uint64 {
-	answer := uint64(C.roaring_bitmap_and_cardinality(rb.cpointer, x2.cpointer))
+func (rb *Bitmap) AndCardinality(x2 ReadOnlyBitmap) uint64 {
+	rb.check("AndCardinality")
+	answer := uint64(C.roaring_bitmap_and_cardinality(rb.cpointer, x2.cbitmap("AndCardinality")))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 	return answer
 }
 
 // XorCardinality computes the size of the symmetric difference between two bitmaps
-func (rb *Bitmap) XorCardinality(x2 *Bitmap) uint64 {
-	answer := uint64(C.roaring_bitmap_xor_cardinality(rb.cpointer, x2.cpointer))
+func (rb *Bitmap) XorCardinality(x2 ReadOnlyBitmap) uint64 {
+	rb.check("XorCardinality")
+	answer := uint64(C.roaring_bitmap_xor_cardinality(rb.cpointer, x2.cbitmap("XorCardinality")))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 	return answer
 }
 
 // OrCardinality computes the size of the union between two bitmaps
-func (rb *Bitmap) OrCardinality(x2 *Bitmap) uint64 {
-	answer := uint64(C.roaring_bitmap_or_cardinality(rb.cpointer, x2.cpointer))
+func (rb *Bitmap) OrCardinality(x2 ReadOnlyBitmap) uint64 {
+	rb.check("OrCardinality")
+	answer := uint64(C.roaring_bitmap_or_cardinality(rb.cpointer, x2.cbitmap("OrCardinality")))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 	return answer
 }
 
 // AndNotCardinality computes the size of the difference between two bitmaps
-func (rb *Bitmap) AndNotCardinality(x2 *Bitmap) uint64 {
-	answer := uint64(C.roaring_bitmap_andnot_cardinality(rb.cpointer, x2.cpointer))
+func (rb *Bitmap) AndNotCardinality(x2 ReadOnlyBitmap) uint64 {
+	rb.check("AndNotCardinality")
+	answer := uint64(C.roaring_bitmap_andnot_cardinality(rb.cpointer, x2.cbitmap("AndNotCardinality")))
 	runtime.KeepAlive(rb)
 	runtime.KeepAlive(x2)
 	return answer
@@ -324,76 +467,78 @@ func (rb *Bitmap) AndNotCardinality(x2 *Bitmap) uint64 {
 
 // Or computes the union between two bitmaps and returns the result
 // This function may panic if the allocation failed.
-func Or(x1, x2 *Bitmap) *Bitmap {
-	b := &Bitmap{C.roaring_bitmap_or(x1.cpointer, x2.cpointer)}
+func Or(x1, x2 ReadOnlyBitmap) *Bitmap {
+	b := &Bitmap{cpointer: C.roaring_bitmap_or(x1.cbitmap("Or"), x2.cbitmap("Or"))}
 	runtime.KeepAlive(x1)
 	runtime.KeepAlive(x2)
 	if b.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(b, free)
+	setFinalizer(b)
 	return b
 }
 
 // And computes the intersection between two bitmaps and returns the result
 // This function may panic if the allocation failed.
-func And(x1, x2 *Bitmap) *Bitmap {
-	b := &Bitmap{C.roaring_bitmap_and(x1.cpointer, x2.cpointer)}
+func And(x1, x2 ReadOnlyBitmap) *Bitmap {
+	b := &Bitmap{cpointer: C.roaring_bitmap_and(x1.cbitmap("And"), x2.cbitmap("And"))}
 	runtime.KeepAlive(x1)
 	runtime.KeepAlive(x2)
 	if b.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(b, free)
+	setFinalizer(b)
 	return b
 }
 
 // Xor computes the symmetric difference between two bitmaps and returns the result
 // This function may panic if the allocation failed.
-func Xor(x1, x2 *Bitmap) *Bitmap {
-	b := &Bitmap{C.roaring_bitmap_xor(x1.cpointer, x2.cpointer)}
+func Xor(x1, x2 ReadOnlyBitmap) *Bitmap {
+	b := &Bitmap{cpointer: C.roaring_bitmap_xor(x1.cbitmap("Xor"), x2.cbitmap("Xor"))}
 	runtime.KeepAlive(x1)
 	runtime.KeepAlive(x2)
 	if b.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(b, free)
+	setFinalizer(b)
 	return b
 }
 
 // AndNot computes the difference between two bitmaps and returns the result
 // This function may panic if the allocation failed.
-func AndNot(x1, x2 *Bitmap) *Bitmap {
-	b := &Bitmap{C.roaring_bitmap_andnot(x1.cpointer, x2.cpointer)}
+func AndNot(x1, x2 ReadOnlyBitmap) *Bitmap {
+	b := &Bitmap{cpointer: C.roaring_bitmap_andnot(x1.cbitmap("AndNot"), x2.cbitmap("AndNot"))}
 	runtime.KeepAlive(x1)
 	runtime.KeepAlive(x2)
 	if b.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(b, free)
+	setFinalizer(b)
 	return b
 }
 
 // Flip negates the bits in the given range (i.e., [rangeStart,rangeEnd)), any integer present in this range and in the bitmap is removed.
 func (rb *Bitmap) Flip(rangeStart, rangeEnd uint64) {
+	rb.check("Flip")
 	C.roaring_bitmap_flip_inplace(rb.cpointer, C.uint64_t(rangeStart), C.uint64_t(rangeEnd))
 	runtime.KeepAlive(rb)
 }
 
 // Flip negates the bits in the given range  (i.e., [rangeStart,rangeEnd)), any integer present in this range and in the bitmap is removed.
 // This function may panic if the allocation failed.
-func Flip(bm *Bitmap, rangeStart, rangeEnd uint64) *Bitmap {
-	b := &Bitmap{C.roaring_bitmap_flip(bm.cpointer, C.uint64_t(rangeStart), C.uint64_t(rangeEnd))}
+func Flip(bm ReadOnlyBitmap, rangeStart, rangeEnd uint64) *Bitmap {
+	b := &Bitmap{cpointer: C.roaring_bitmap_flip(bm.cbitmap("Flip"), C.uint64_t(rangeStart), C.uint64_t(rangeEnd))}
 	if b.cpointer == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(b, free)
+	setFinalizer(b)
 	runtime.KeepAlive(bm)
 	return b
 }
 
 // SerializedSizeInBytes computes the serialized size in bytes  the Bitmap.
 func (rb *Bitmap) SerializedSizeInBytes() int {
+	rb.check("SerializedSizeInBytes")
 	answer := int(C.roaring_bitmap_portable_size_in_bytes(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -401,6 +546,7 @@ func (rb *Bitmap) SerializedSizeInBytes() int {
 
 // FrozenSizeInBytes computes the frozen serialized size in bytes
 func (rb *Bitmap) FrozenSizeInBytes() int {
+	rb.check("FrozenSizeInBytes")
 	answer := int(C.roaring_bitmap_frozen_size_in_bytes(rb.cpointer))
 	runtime.KeepAlive(rb)
 	return answer
@@ -413,58 +559,261 @@ type IntIterable interface {
 	Next() uint32
 }
 
+// ManyIntIterable allows you to iterate over the values in a Bitmap in batches
+type ManyIntIterable interface {
+	// NextMany fills buf with the next integers and returns how many were written,
+	// 0 means that the iteration is over
+	NextMany(buf []uint32) int
+}
+
+// SeekableIntIterable allows you to move back and forth over the values in a Bitmap.
+// The iterator sits between two integers: Next returns the integer after it and
+// Previous the integer before it, so that Previous undoes Next.
+type SeekableIntIterable interface {
+	IntIterable
+	ManyIntIterable
+	// PeekNext returns the next integer without moving, HasNext must be true
+	PeekNext() uint32
+	// AdvanceIfNeeded moves forward until the next integer is at least minval
+	AdvanceIfNeeded(minval uint32)
+	// HasPrevious returns true if there are integers before the iterator
+	HasPrevious() bool
+	// Previous moves backward and returns the previous integer, HasPrevious must be true
+	Previous() uint32
+	// Skip moves forward over at most n integers and returns how many were skipped
+	Skip(n uint32) uint32
+	// SkipBackward moves backward over at most n integers and returns how many were skipped
+	SkipBackward(n uint32) uint32
+}
+
+// intIterator wraps a CRoaring iterator positioned on the integer that Next returns
 type intIterator struct {
 	pointertonext *C.roaring_uint32_iterator_t
-	current       uint32
-	has_next      bool
+	bitmap        *Bitmap
 }
 
 // Iterator creates a new IntIterable to iterate over the integers contained in the bitmap, in sorted order
 func (rb *Bitmap) Iterator() IntIterable {
+	rb.check("Iterator")
+	return newIntIterator(rb)
+}
+
+// ManyIterator creates a new ManyIntIterable to iterate over the integers contained in the bitmap, in sorted order
+func (rb *Bitmap) ManyIterator() ManyIntIterable {
+	rb.check("ManyIterator")
+	return newIntIterator(rb)
+}
+
+// SeekableIterator creates a new SeekableIntIterable positioned before the smallest integer contained in the bitmap
+func (rb *Bitmap) SeekableIterator() SeekableIntIterable {
+	rb.check("SeekableIterator")
 	return newIntIterator(rb)
 }
 
 // HasNext returns true if there are more integers to iterate over
 func (ii *intIterator) HasNext() bool {
-	return ii.has_next
+	ii.bitmap.check("HasNext")
+	answer := bool(ii.pointertonext.has_value)
+	runtime.KeepAlive(ii)
+	return answer
 }
 
 // Next returns the next integer
 func (ii *intIterator) Next() uint32 {
-	answer := ii.current
-	ii.has_next = bool(ii.pointertonext.has_value)
-	ii.current = uint32(ii.pointertonext.current_value)
+	ii.bitmap.check("Next")
+	answer := uint32(ii.pointertonext.current_value)
 	C.roaring_uint32_iterator_advance(ii.pointertonext)
 	runtime.KeepAlive(ii)
 	return answer
 }
 
+// NextMany fills buf with the next integers using a single call into C, it returns how many were written
+func (ii *intIterator) NextMany(buf []uint32) int {
+	ii.bitmap.check("NextMany")
+	if len(buf) == 0 {
+		return 0
+	}
+	answer := int(C.roaring_uint32_iterator_read(ii.pointertonext, (*C.uint32_t)(unsafe.Pointer(&buf[0])), C.uint32_t(len(buf))))
+	runtime.KeepAlive(buf)
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+// PeekNext returns the next integer without moving
+func (ii *intIterator) PeekNext() uint32 {
+	ii.bitmap.check("PeekNext")
+	answer := uint32(ii.pointertonext.current_value)
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+// AdvanceIfNeeded moves forward until the next integer is at least minval
+func (ii *intIterator) AdvanceIfNeeded(minval uint32) {
+	ii.bitmap.check("AdvanceIfNeeded")
+	if ii.pointertonext.has_value && uint32(ii.pointertonext.current_value) < minval {
+		C.roaring_uint32_iterator_move_equalorlarger(ii.pointertonext, C.uint32_t(minval))
+	}
+	runtime.KeepAlive(ii)
+}
+
+// HasPrevious returns true if there are integers before the iterator
+func (ii *intIterator) HasPrevious() bool {
+	ii.bitmap.check("HasPrevious")
+	answer := bool(C.gocroaring_iterator_has_previous(ii.pointertonext))
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+// Previous moves backward and returns the previous integer
+func (ii *intIterator) Previous() uint32 {
+	ii.bitmap.check("Previous")
+	C.gocroaring_iterator_previous(ii.pointertonext)
+	answer := uint32(ii.pointertonext.current_value)
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+// Skip moves forward over at most n integers and returns how many were skipped
+func (ii *intIterator) Skip(n uint32) uint32 {
+	ii.bitmap.check("Skip")
+	answer := uint32(C.roaring_uint32_iterator_skip(ii.pointertonext, C.uint32_t(n)))
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+// SkipBackward moves backward over at most n integers and returns how many were skipped
+func (ii *intIterator) SkipBackward(n uint32) uint32 {
+	ii.bitmap.check("SkipBackward")
+	answer := uint32(C.gocroaring_iterator_skip_backward(ii.pointertonext, C.uint32_t(n)))
+	runtime.KeepAlive(ii)
+	return answer
+}
+
 func freeIntIterator(a *intIterator) {
+	untrack(unsafe.Pointer(a.pointertonext), true)
 	C.roaring_uint32_iterator_free(a.pointertonext)
 	runtime.KeepAlive(a)
 }
 
+// free releases the C iterator without waiting for the finalizer
+func (ii *intIterator) free() {
+	runtime.SetFinalizer(ii, nil)
+	untrack(unsafe.Pointer(ii.pointertonext), false)
+	freeIntIterator(ii)
+}
+
 // This function may panic if the allocation failed.
 func newIntIterator(a *Bitmap) *intIterator {
 	p := new(intIterator)
 	p.pointertonext = C.roaring_iterator_create(a.cpointer)
-	p.has_next = bool(p.pointertonext.has_value)
-	p.current = uint32(p.pointertonext.current_value)
-	if p.has_next {
-		C.roaring_uint32_iterator_advance(p.pointertonext)
+	if p.pointertonext == nil {
+		panic("C code returned a null pointer.")
 	}
+	// the C iterator points into the bitmap, so we keep it alive
+	p.bitmap = a
+	track(unsafe.Pointer(p.pointertonext), kindIterator)
+	runtime.SetFinalizer(p, freeIntIterator)
+	return p
+}
+
+// reverseIntIterator wraps a CRoaring iterator that goes from the largest integer to the smallest
+type reverseIntIterator struct {
+	pointertonext *C.roaring_uint32_iterator_t
+	bitmap        *Bitmap
+}
+
+// ReverseIterator creates a new IntIterable to iterate over the integers contained in the bitmap, in decreasing order
+func (rb *Bitmap) ReverseIterator() IntIterable {
+	rb.check("ReverseIterator")
+	return newReverseIntIterator(rb)
+}
+
+// ReverseManyIterator creates a new ManyIntIterable to iterate over the integers contained in the bitmap, in decreasing order
+func (rb *Bitmap) ReverseManyIterator() ManyIntIterable {
+	rb.check("ReverseManyIterator")
+	return newReverseIntIterator(rb)
+}
+
+// HasNext returns true if there are more integers to iterate over
+func (ii *reverseIntIterator) HasNext() bool {
+	ii.bitmap.check("HasNext")
+	answer := bool(ii.pointertonext.has_value)
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+// Next returns the next integer
+func (ii *reverseIntIterator) Next() uint32 {
+	ii.bitmap.check("Next")
+	answer := uint32(ii.pointertonext.current_value)
+	C.roaring_uint32_iterator_previous(ii.pointertonext)
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+// NextMany fills buf with the next integers using a single call into C, it returns how many were written
+func (ii *reverseIntIterator) NextMany(buf []uint32) int {
+	ii.bitmap.check("NextMany")
+	if len(buf) == 0 {
+		return 0
+	}
+	answer := int(C.gocroaring_iterator_read_backward(ii.pointertonext, (*C.uint32_t)(unsafe.Pointer(&buf[0])), C.uint32_t(len(buf))))
+	runtime.KeepAlive(buf)
+	runtime.KeepAlive(ii)
+	return answer
+}
+
+func freeReverseIntIterator(a *reverseIntIterator) {
+	untrack(unsafe.Pointer(a.pointertonext), true)
+	C.roaring_uint32_iterator_free(a.pointertonext)
 	runtime.KeepAlive(a)
+}
+
+// free releases the C iterator without waiting for the finalizer
+func (ii *reverseIntIterator) free() {
+	runtime.SetFinalizer(ii, nil)
+	untrack(unsafe.Pointer(ii.pointertonext), false)
+	freeReverseIntIterator(ii)
+}
+
+// This function may panic if the allocation failed.
+func newReverseIntIterator(a *Bitmap) *reverseIntIterator {
+	p := new(reverseIntIterator)
+	p.pointertonext = C.roaring_iterator_create(a.cpointer)
 	if p.pointertonext == nil {
 		panic("C code returned a null pointer.")
 	}
-	runtime.SetFinalizer(p, freeIntIterator)
+	C.roaring_iterator_init_last(a.cpointer, p.pointertonext)
+	// the C iterator points into the bitmap, so we keep it alive
+	p.bitmap = a
+	track(unsafe.Pointer(p.pointertonext), kindIterator)
+	runtime.SetFinalizer(p, freeReverseIntIterator)
 	return p
 }
 
+// TopK returns the (at most) k largest integers contained in the bitmap, in decreasing order
+func (rb *Bitmap) TopK(k int) []uint32 {
+	rb.check("TopK")
+	if k < 0 {
+		k = 0
+	}
+	if card := rb.Cardinality(); uint64(k) > card {
+		k = int(card)
+	}
+	answer := make([]uint32, k)
+	if k > 0 {
+		it := newReverseIntIterator(rb)
+		it.NextMany(answer)
+		it.free()
+	}
+	return answer
+}
+
 // Write writes a serialized version of this bitmap to stream (you should have enough space)
 func (rb *Bitmap) Write(b []byte) error {
+	rb.check("Write")
 	if len(b) < rb.SerializedSizeInBytes() {
-		return errors.New("not enough space")
+		return ErrBufferTooSmall
 	}
 	bchar := (*C.char)(unsafe.Pointer(&b[0]))
 	C.roaring_bitmap_portable_serialize(rb.cpointer, bchar)
@@ -475,8 +824,9 @@ func (rb *Bitmap) Write(b []byte) error {
 
 // WriteFrozen writes a serialized version of bitmap to the stream in the Frozen format
 func (rb *Bitmap) WriteFrozen(b []byte) error {
+	rb.check("WriteFrozen")
 	if len(b) < rb.FrozenSizeInBytes() {
-		return errors.New("not enough space")
+		return ErrBufferTooSmall
 	}
 	bchar := (*C.char)(unsafe.Pointer(&b[0]))
 	C.roaring_bitmap_frozen_serialize(rb.cpointer, bchar)
@@ -487,6 +837,7 @@ func (rb *Bitmap) WriteFrozen(b []byte) error {
 
 // ToArray creates a new slice containing all of the integers stored in the Bitmap in sorted order
 func (rb *Bitmap) ToArray() []uint32 {
+	rb.check("ToArray")
 	card := rb.Cardinality()
 	array := make([]uint32, card)
 	if card > 0 {
@@ -498,6 +849,7 @@ func (rb *Bitmap) ToArray() []uint32 {
 
 // String creates a string representation of the Bitmap
 func (rb *Bitmap) String() string {
+	rb.check("String")
 	arr := rb.ToArray() // todo: replace with an iterator
 	var buffer bytes.Buffer
 	start := []byte("{")
@@ -520,35 +872,77 @@ func (rb *Bitmap) String() string {
 
 // Read reads a serialized version of the bitmap (you need to call Free on it once you are done)
 func Read(b []byte) (*Bitmap, error) {
+	if len(b) == 0 {
+		return nil, ErrEmpty
+	}
 	bchar := (*C.char)(unsafe.Pointer(&b[0]))
-	answer := &Bitmap{C.roaring_bitmap_portable_deserialize_safe(bchar, C.size_t(len(b)))}
+	answer := &Bitmap{cpointer: C.roaring_bitmap_portable_deserialize_safe(bchar, C.size_t(len(b)))}
 	runtime.KeepAlive(b)
 	if answer.cpointer == nil {
-		return nil, errors.New("failed to read roaring array")
+		return nil, ErrCorrupt
 	}
-	runtime.SetFinalizer(answer, free)
+	setFinalizer(answer)
 	return answer, nil
 }
 
 // ReadFrozenView reads a frozen serialized version of the bitmap
-// this is immutable and attempting to mutate it will fail catastrophically
-// It keeps a reference to the buffer internally to make sure it's alive for
-// the complete lifetime of the view
-func ReadFrozenView(b []byte) (*Bitmap, error) {
-	bchar := (*C.char)(unsafe.Pointer(&b[0]))
-	answer := &frozenBitmap{
-		Bitmap{C.roaring_bitmap_frozen_view(bchar, C.size_t(len(b)))},
-		&b[0],
+// The result is a read-only view over the buffer, call Clone to get a mutable copy.
+// The buffer is pinned for the lifetime of the view, or copied if it is not 32-byte aligned,
+// see ReadFrozenViewMode. Before Go 1.21, which cannot pin memory, the buffer is always copied.
+func ReadFrozenView(b []byte) (*ImmutableBitmap, error) {
+	return ReadFrozenViewMode(b, PinBuffer)
+}
+
+// ReadFrozenViewMode reads a frozen serialized version of the bitmap, with the buffer
+// either pinned or copied into C memory, see ViewMode.
+// The result is a read-only view, call Clone to get a mutable copy.
+func ReadFrozenViewMode(b []byte, mode ViewMode) (*ImmutableBitmap, error) {
+	if len(b) == 0 {
+		return nil, ErrEmpty
 	}
-	if answer.cpointer == nil {
-		return nil, errors.New("failed to read roaring array")
+	bchar, buffer, release := viewMemory(b, 32, mode)
+	answer := newImmutableBitmap(C.roaring_bitmap_frozen_view(bchar, C.size_t(len(b))), buffer, release)
+	if answer == nil {
+		release()
+		return nil, ErrCorrupt
 	}
-	runtime.SetFinalizer(&answer.Bitmap, free)
-	return &answer.Bitmap, nil
+	return answer, nil
+}
+
+// ReadPortableView reads a serialized version of the bitmap in the portable format without copying it,
+// the containers of the result point into the buffer.
+// The result is a read-only view over the buffer, call Clone to get a mutable copy.
+// The buffer is pinned for the lifetime of the view, see ReadPortableViewMode. Before Go 1.21,
+// which cannot pin memory, the buffer is copied and later writes to it are not seen by the view.
+func ReadPortableView(b []byte) (*ImmutableBitmap, error) {
+	return ReadPortableViewMode(b, PinBuffer)
+}
+
+// ReadPortableViewMode reads a serialized version of the bitmap in the portable format, with
+// the buffer either pinned or copied into C memory, see ViewMode.
+// The result is a read-only view, call Clone to get a mutable copy.
+func ReadPortableViewMode(b []byte, mode ViewMode) (*ImmutableBitmap, error) {
+	if len(b) == 0 {
+		return nil, ErrEmpty
+	}
+	// roaring_bitmap_portable_deserialize_frozen trusts its input, so we check it first
+	size := C.roaring_bitmap_portable_deserialize_size((*C.char)(unsafe.Pointer(&b[0])), C.size_t(len(b)))
+	runtime.KeepAlive(b)
+	if size == 0 {
+		return nil, ErrCorrupt
+	}
+	bchar, buffer, release := viewMemory(b[:size], 1, mode)
+	answer := newImmutableBitmap(C.roaring_bitmap_portable_deserialize_frozen(bchar), buffer, release)
+	if answer == nil {
+		release()
+		return nil, ErrCorrupt
+	}
+	return answer, nil
 }
 
 // Stats returns some statistics about the roaring bitmap.
 func (rb *Bitmap) Stats() map[string]uint64 {
+	rb.check("Stats")
 	var stat C.roaring_statistics_t
 	C.roaring_bitmap_statistics(rb.cpointer, &stat)
 	runtime.KeepAlive(rb)
@@ -588,6 +982,7 @@ type Statistics struct {
 
 // StatsStruct - same as Stats but returns typed struct. See https://github.com/RoaringBitmap/roaring/pull/73 for rationale
 func (rb *Bitmap) StatsStruct() Statistics {
+	rb.check("StatsStruct")
 	var stat C.roaring_statistics_t
 	C.roaring_bitmap_statistics(rb.cpointer, &stat)
 	stats := Statistics{
@@ -0,0 +1,743 @@
+package gocroaring
+
+/*
+#cgo CFLAGS: -O3  -std=c11
+#include "roaring.h"
+
+typedef struct {
+	roaring64_bitmap_t *r;
+	roaring64_bulk_context_t context;
+	uint64_t high;
+} gocroaring_prefix_adder_t;
+
+static bool gocroaring_add_with_prefix(uint32_t value, void *param) {
+	gocroaring_prefix_adder_t *adder = (gocroaring_prefix_adder_t *)param;
+	roaring64_bitmap_add_bulk(adder->r, &adder->context, adder->high | value);
+	return true;
+}
+
+// gocroaring_bitmap_to_64 returns a new 64-bit bitmap holding the values of r
+// with their high 32 bits set to high. It returns NULL on allocation failure.
+static roaring64_bitmap_t *gocroaring_bitmap_to_64(const roaring_bitmap_t *r, uint32_t high) {
+	if (high == 0) {
+		roaring_bitmap_t *copy = roaring_bitmap_copy(r);
+		if (copy == NULL) {
+			return NULL;
+		}
+		roaring64_bitmap_t *answer = roaring64_bitmap_move_from_roaring32(copy);
+		roaring_bitmap_free(copy);
+		return answer;
+	}
+	gocroaring_prefix_adder_t adder = {0};
+	adder.r = roaring64_bitmap_create();
+	if (adder.r == NULL) {
+		return NULL;
+	}
+	adder.high = (uint64_t)high << 32;
+	roaring_iterate(r, gocroaring_add_with_prefix, &adder);
+	return adder.r;
+}
+
+// The ART nodes of the frozen 64-bit format are CRoaring's in-memory nodes (art_leaf_t,
+// art_node4_t... in roaring.c, which roaring.h does not expose). These mirror their layout
+// so that the compiler gives their sizes on the target platform.
+#define GOCROARING_ART_KEY_BYTES 6
+typedef struct {
+	uint8_t prefix_size;
+	uint8_t prefix[GOCROARING_ART_KEY_BYTES - 1];
+} gocroaring_art_inner_node_t;
+typedef union {
+	struct { uint8_t key[GOCROARING_ART_KEY_BYTES]; uint64_t val; };
+	uint64_t next_free;
+} gocroaring_art_leaf_t;
+typedef union {
+	struct { gocroaring_art_inner_node_t base; uint8_t count; uint8_t keys[4]; uint64_t children[4]; };
+	uint64_t next_free;
+} gocroaring_art_node4_t;
+typedef union {
+	struct { gocroaring_art_inner_node_t base; uint8_t count; uint8_t keys[16]; uint64_t children[16]; };
+	uint64_t next_free;
+} gocroaring_art_node16_t;
+typedef union {
+	struct { gocroaring_art_inner_node_t base; uint8_t count; uint64_t available_children; uint8_t keys[256]; uint64_t children[48]; };
+	uint64_t next_free;
+} gocroaring_art_node48_t;
+typedef union {
+	struct { gocroaring_art_inner_node_t base; uint16_t count; uint64_t children[256]; };
+	uint64_t next_free;
+} gocroaring_art_node256_t;
+
+*/
+import "C"
+import (
+	"bytes"
+	"encoding/binary"
+	"runtime"
+	"strconv"
+	"unsafe"
+)
+
+func free64(a *Bitmap64) {
+	untrack(unsafe.Pointer(a.cpointer), true)
+	C.roaring64_bitmap_free(a.cpointer)
+	a.cpointer = nil
+}
+
+// setFinalizer64 makes sure the C bitmap is freed along with b
+func setFinalizer64(b *Bitmap64) {
+	track(unsafe.Pointer(b.cpointer), kindBitmap64)
+	runtime.SetFinalizer(b, free64)
+}
+
+// Bitmap64 is the roaring bitmap for 64-bit integers
+type Bitmap64 struct {
+	cpointer *C.roaring64_bitmap_t
+}
+
+// check panics, naming the operation, if the bitmap was freed
+func (rb *Bitmap64) check(op string) {
+	if rb.cpointer == nil {
+		panic("gocroaring: " + op + " called on a freed bitmap")
+	}
+}
+
+// New64 creates a new Bitmap64 with any number of initial values.
+// This function may panic if the allocation failed.
+func New64(x ...uint64) *Bitmap64 {
+	var answer *Bitmap64
+	if len(x) > 0 {
+		ptr := unsafe.Pointer(&x[0])
+		answer = &Bitmap64{C.roaring64_bitmap_of_ptr(C.size_t(len(x)), (*C.uint64_t)(ptr))}
+		runtime.KeepAlive(x)
+	} else {
+		answer = &Bitmap64{C.roaring64_bitmap_create()}
+	}
+	if answer.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(answer)
+	return answer
+}
+
+// Free releases the memory held by the bitmap right away, instead of waiting for the garbage collector.
+// The bitmap must not be used afterwards: its methods panic. Calling Free again does nothing.
+func (rb *Bitmap64) Free() {
+	if rb.cpointer == nil {
+		return
+	}
+	// Clear the finalizer to avoid double frees
+	runtime.SetFinalizer(rb, nil)
+	untrack(unsafe.Pointer(rb.cpointer), false)
+	free64(rb)
+}
+
+// Add the integer(s) x to the bitmap
+func (rb *Bitmap64) Add(x ...uint64) {
+	rb.check("Add")
+	if len(x) == 1 {
+		C.roaring64_bitmap_add(rb.cpointer, C.uint64_t(x[0]))
+	} else if len(x) > 1 {
+		ptr := unsafe.Pointer(&x[0])
+		C.roaring64_bitmap_add_many(rb.cpointer, C.size_t(len(x)), (*C.uint64_t)(ptr))
+		runtime.KeepAlive(x)
+	}
+	runtime.KeepAlive(rb)
+}
+
+// AddRange - add all values in range [min, max)
+func (rb *Bitmap64) AddRange(min, max uint64) {
+	rb.check("AddRange")
+	C.roaring64_bitmap_add_range(rb.cpointer, C.uint64_t(min), C.uint64_t(max))
+	runtime.KeepAlive(rb)
+}
+
+// RemoveRange - remove all values in range [min, max)
+func (rb *Bitmap64) RemoveRange(min, max uint64) {
+	rb.check("RemoveRange")
+	C.roaring64_bitmap_remove_range(rb.cpointer, C.uint64_t(min), C.uint64_t(max))
+	runtime.KeepAlive(rb)
+}
+
+// RunOptimize the compression of the bitmap (call this after populating a new bitmap), return true if the bitmap has at least one run container
+func (rb *Bitmap64) RunOptimize() bool {
+	rb.check("RunOptimize")
+	answer := bool(C.roaring64_bitmap_run_optimize(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// Contains returns true if the integer is contained in the bitmap
+func (rb *Bitmap64) Contains(x uint64) bool {
+	rb.check("Contains")
+	answer := bool(C.roaring64_bitmap_contains(rb.cpointer, C.uint64_t(x)))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// ContainsRange returns true if the integers in the range [x, y) are contained in the bitmap
+func (rb *Bitmap64) ContainsRange(x, y uint64) bool {
+	rb.check("ContainsRange")
+	answer := bool(C.roaring64_bitmap_contains_range(rb.cpointer, C.uint64_t(x), C.uint64_t(y)))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// Clear removes all elements from the bitmap
+func (rb *Bitmap64) Clear() {
+	rb.check("Clear")
+	C.roaring64_bitmap_clear(rb.cpointer)
+	runtime.KeepAlive(rb)
+}
+
+// Remove the integer x from the bitmap
+func (rb *Bitmap64) Remove(x uint64) {
+	rb.check("Remove")
+	C.roaring64_bitmap_remove(rb.cpointer, C.uint64_t(x))
+	runtime.KeepAlive(rb)
+}
+
+// Cardinality returns the number of integers contained in the bitmap
+func (rb *Bitmap64) Cardinality() uint64 {
+	rb.check("Cardinality")
+	answer := uint64(C.roaring64_bitmap_get_cardinality(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// GetCardinality returns the number of integers contained in the bitmap
+func (rb *Bitmap64) GetCardinality() uint64 {
+	rb.check("GetCardinality")
+	return rb.Cardinality()
+}
+
+// Maximum returns the largest of the integers contained in the bitmap assuming that it is not empty
+func (rb *Bitmap64) Maximum() uint64 {
+	rb.check("Maximum")
+	answer := uint64(C.roaring64_bitmap_maximum(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// Minimum returns the smallest of the integers contained in the bitmap assuming that it is not empty
+func (rb *Bitmap64) Minimum() uint64 {
+	rb.check("Minimum")
+	answer := uint64(C.roaring64_bitmap_minimum(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// MaximumOK returns the largest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (rb *Bitmap64) MaximumOK() (x uint64, ok bool) {
+	rb.check("MaximumOK")
+	if rb.IsEmpty() {
+		return 0, false
+	}
+	return rb.Maximum(), true
+}
+
+// MinimumOK returns the smallest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (rb *Bitmap64) MinimumOK() (x uint64, ok bool) {
+	rb.check("MinimumOK")
+	if rb.IsEmpty() {
+		return 0, false
+	}
+	return rb.Minimum(), true
+}
+
+// Rank returns the number of values smaller or equal to x
+func (rb *Bitmap64) Rank(x uint64) uint64 {
+	rb.check("Rank")
+	answer := uint64(C.roaring64_bitmap_rank(rb.cpointer, C.uint64_t(x)))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// Select returns the element having the designated rank, if it exists
+func (rb *Bitmap64) Select(rank uint64) (uint64, error) {
+	rb.check("Select")
+	var element uint64 = 0
+	exists := bool(C.roaring64_bitmap_select(rb.cpointer, C.uint64_t(rank), (*C.uint64_t)(unsafe.Pointer(&element))))
+	runtime.KeepAlive(rb)
+	if exists {
+		return element, nil
+	}
+	return element, ErrNoSuchElement
+}
+
+// IsEmpty returns true if the Bitmap64 is empty (it is faster than doing (Cardinality() == 0))
+func (rb *Bitmap64) IsEmpty() bool {
+	rb.check("IsEmpty")
+	answer := bool(C.roaring64_bitmap_is_empty(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// Equals returns true if the two bitmaps contain the same integers
+func (rb *Bitmap64) Equals(o interface{}) bool {
+	rb.check("Equals")
+	srb, ok := o.(ReadOnlyBitmap64)
+	if ok {
+		answer := bool(C.roaring64_bitmap_equals(rb.cpointer, srb.cbitmap64("Equals")))
+		runtime.KeepAlive(rb)
+		runtime.KeepAlive(srb)
+		return answer
+	}
+	return false
+}
+
+// Clone creates a copy of the Bitmap64
+// This function may panic if the allocation failed.
+func (rb *Bitmap64) Clone() *Bitmap64 {
+	rb.check("Clone")
+	b := &Bitmap64{C.roaring64_bitmap_copy(rb.cpointer)}
+	runtime.KeepAlive(rb)
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(b)
+	return b
+}
+
+// And computes the intersection between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) And(x2 ReadOnlyBitmap64) {
+	rb.check("And")
+	C.roaring64_bitmap_and_inplace(rb.cpointer, x2.cbitmap64("And"))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+}
+
+// Xor computes the symmetric difference between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) Xor(x2 ReadOnlyBitmap64) {
+	rb.check("Xor")
+	C.roaring64_bitmap_xor_inplace(rb.cpointer, x2.cbitmap64("Xor"))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+}
+
+// Or computes the union between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) Or(x2 ReadOnlyBitmap64) {
+	rb.check("Or")
+	C.roaring64_bitmap_or_inplace(rb.cpointer, x2.cbitmap64("Or"))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+}
+
+// AndNot computes the difference between two bitmaps and stores the result in the current bitmap
+func (rb *Bitmap64) AndNot(x2 ReadOnlyBitmap64) {
+	rb.check("AndNot")
+	C.roaring64_bitmap_andnot_inplace(rb.cpointer, x2.cbitmap64("AndNot"))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+}
+
+// Intersect checks whether the two bitmaps intersect
+func (rb *Bitmap64) Intersect(x2 ReadOnlyBitmap64) bool {
+	rb.check("Intersect")
+	answer := bool(C.roaring64_bitmap_intersect(rb.cpointer, x2.cbitmap64("Intersect")))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+	return answer
+}
+
+// JaccardIndex computes the Jaccard index between two bitmaps
+func (rb *Bitmap64) JaccardIndex(x2 ReadOnlyBitmap64) float64 {
+	rb.check("JaccardIndex")
+	answer := float64(C.roaring64_bitmap_jaccard_index(rb.cpointer, x2.cbitmap64("JaccardIndex")))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+	return answer
+}
+
+// AndCardinality computes the size of the intersection between two bitmaps
+func (rb *Bitmap64) AndCardinality(x2 ReadOnlyBitmap64) uint64 {
+	rb.check("AndCardinality")
+	answer := uint64(C.roaring64_bitmap_and_cardinality(rb.cpointer, x2.cbitmap64("AndCardinality")))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+	return answer
+}
+
+// XorCardinality computes the size of the symmetric difference between two bitmaps
+func (rb *Bitmap64) XorCardinality(x2 ReadOnlyBitmap64) uint64 {
+	rb.check("XorCardinality")
+	answer := uint64(C.roaring64_bitmap_xor_cardinality(rb.cpointer, x2.cbitmap64("XorCardinality")))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+	return answer
+}
+
+// OrCardinality computes the size of the union between two bitmaps
+func (rb *Bitmap64) OrCardinality(x2 ReadOnlyBitmap64) uint64 {
+	rb.check("OrCardinality")
+	answer := uint64(C.roaring64_bitmap_or_cardinality(rb.cpointer, x2.cbitmap64("OrCardinality")))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+	return answer
+}
+
+// AndNotCardinality computes the size of the difference between two bitmaps
+func (rb *Bitmap64) AndNotCardinality(x2 ReadOnlyBitmap64) uint64 {
+	rb.check("AndNotCardinality")
+	answer := uint64(C.roaring64_bitmap_andnot_cardinality(rb.cpointer, x2.cbitmap64("AndNotCardinality")))
+	runtime.KeepAlive(rb)
+	runtime.KeepAlive(x2)
+	return answer
+}
+
+// Or64 computes the union between two bitmaps and returns the result
+// This function may panic if the allocation failed.
+func Or64(x1, x2 ReadOnlyBitmap64) *Bitmap64 {
+	b := &Bitmap64{C.roaring64_bitmap_or(x1.cbitmap64("Or64"), x2.cbitmap64("Or64"))}
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(b)
+	return b
+}
+
+// And64 computes the intersection between two bitmaps and returns the result
+// This function may panic if the allocation failed.
+func And64(x1, x2 ReadOnlyBitmap64) *Bitmap64 {
+	b := &Bitmap64{C.roaring64_bitmap_and(x1.cbitmap64("And64"), x2.cbitmap64("And64"))}
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(b)
+	return b
+}
+
+// Xor64 computes the symmetric difference between two bitmaps and returns the result
+// This function may panic if the allocation failed.
+func Xor64(x1, x2 ReadOnlyBitmap64) *Bitmap64 {
+	b := &Bitmap64{C.roaring64_bitmap_xor(x1.cbitmap64("Xor64"), x2.cbitmap64("Xor64"))}
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(b)
+	return b
+}
+
+// AndNot64 computes the difference between two bitmaps and returns the result
+// This function may panic if the allocation failed.
+func AndNot64(x1, x2 ReadOnlyBitmap64) *Bitmap64 {
+	b := &Bitmap64{C.roaring64_bitmap_andnot(x1.cbitmap64("AndNot64"), x2.cbitmap64("AndNot64"))}
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(b)
+	return b
+}
+
+// Flip negates the bits in the given range (i.e., [rangeStart,rangeEnd)), any integer present in this range and in the bitmap is removed.
+func (rb *Bitmap64) Flip(rangeStart, rangeEnd uint64) {
+	rb.check("Flip")
+	C.roaring64_bitmap_flip_inplace(rb.cpointer, C.uint64_t(rangeStart), C.uint64_t(rangeEnd))
+	runtime.KeepAlive(rb)
+}
+
+// Flip64 negates the bits in the given range  (i.e., [rangeStart,rangeEnd)), any integer present in this range and in the bitmap is removed.
+// This function may panic if the allocation failed.
+func Flip64(bm ReadOnlyBitmap64, rangeStart, rangeEnd uint64) *Bitmap64 {
+	b := &Bitmap64{C.roaring64_bitmap_flip(bm.cbitmap64("Flip64"), C.uint64_t(rangeStart), C.uint64_t(rangeEnd))}
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(b)
+	runtime.KeepAlive(bm)
+	return b
+}
+
+// ToArray creates a new slice containing all of the integers stored in the Bitmap64 in sorted order
+func (rb *Bitmap64) ToArray() []uint64 {
+	rb.check("ToArray")
+	card := rb.Cardinality()
+	array := make([]uint64, card)
+	if card > 0 {
+		C.roaring64_bitmap_to_uint64_array(rb.cpointer, (*C.uint64_t)(unsafe.Pointer(&array[0])))
+	}
+	runtime.KeepAlive(rb)
+	return array
+}
+
+// String creates a string representation of the Bitmap64
+func (rb *Bitmap64) String() string {
+	rb.check("String")
+	arr := rb.ToArray()
+	var buffer bytes.Buffer
+	buffer.WriteString("{")
+	l := len(arr)
+	for counter, i := range arr {
+		// to avoid exhausting the memory
+		if counter > 0x40000 {
+			buffer.WriteString("...")
+			break
+		}
+		buffer.WriteString(strconv.FormatUint(i, 10))
+		if counter+1 < l { // there is more
+			buffer.WriteString(",")
+		}
+	}
+	buffer.WriteString("}")
+	return buffer.String()
+}
+
+// SerializedSizeInBytes computes the serialized size in bytes  the Bitmap64.
+func (rb *Bitmap64) SerializedSizeInBytes() int {
+	rb.check("SerializedSizeInBytes")
+	answer := int(C.roaring64_bitmap_portable_size_in_bytes(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// Write writes a serialized version of this bitmap to stream (you should have enough space)
+// The format is compatible with the 64-bit extension of the portable format used by
+// the Go roaring64 package and by Java's Roaring64NavigableMap.
+func (rb *Bitmap64) Write(b []byte) error {
+	rb.check("Write")
+	if len(b) < rb.SerializedSizeInBytes() {
+		return ErrBufferTooSmall
+	}
+	bchar := (*C.char)(unsafe.Pointer(&b[0]))
+	C.roaring64_bitmap_portable_serialize(rb.cpointer, bchar)
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(rb)
+	return nil
+}
+
+// SerializedSize64 returns how many bytes Read64 would consume at the start of b,
+// or 0 if b does not begin with a valid serialized Bitmap64
+func SerializedSize64(b []byte) int {
+	if len(b) == 0 {
+		return 0
+	}
+	bchar := (*C.char)(unsafe.Pointer(&b[0]))
+	answer := int(C.roaring64_bitmap_portable_deserialize_size(bchar, C.size_t(len(b))))
+	runtime.KeepAlive(b)
+	return answer
+}
+
+// Read64 reads a serialized version of the 64-bit bitmap (you need to call Free on it once you are done)
+func Read64(b []byte) (*Bitmap64, error) {
+	if len(b) == 0 {
+		return nil, ErrEmpty
+	}
+	bchar := (*C.char)(unsafe.Pointer(&b[0]))
+	answer := &Bitmap64{C.roaring64_bitmap_portable_deserialize_safe(bchar, C.size_t(len(b)))}
+	runtime.KeepAlive(b)
+	if answer.cpointer == nil {
+		return nil, ErrCorrupt
+	}
+	setFinalizer64(answer)
+	return answer, nil
+}
+
+// ShrinkToFit reallocates the memory used by the bitmap so that no space is wasted, returns the number of bytes saved
+func (rb *Bitmap64) ShrinkToFit() int {
+	rb.check("ShrinkToFit")
+	answer := int(C.roaring64_bitmap_shrink_to_fit(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// compact returns rb if its layout is compact, as the frozen format requires, or else a shrunk copy,
+// so that rb itself is never modified. The caller must Free the result if it is not rb.
+func (rb *Bitmap64) compact() *Bitmap64 {
+	shrunk := C.roaring64_bitmap_frozen_size_in_bytes(rb.cpointer) != 0
+	runtime.KeepAlive(rb)
+	if shrunk {
+		return rb
+	}
+	answer := rb.Clone()
+	answer.ShrinkToFit()
+	return answer
+}
+
+// FrozenSizeInBytes computes the frozen serialized size in bytes
+// The frozen format requires a compact layout: if the bitmap does not have one, the size is
+// computed on a temporary copy. Call ShrinkToFit first to avoid the copy.
+func (rb *Bitmap64) FrozenSizeInBytes() int {
+	rb.check("FrozenSizeInBytes")
+	c := rb.compact()
+	answer := int(C.roaring64_bitmap_frozen_size_in_bytes(c.cpointer))
+	runtime.KeepAlive(c)
+	if c != rb {
+		c.Free()
+	}
+	return answer
+}
+
+// WriteFrozen writes a serialized version of bitmap to the stream in the Frozen format
+// The frozen format is specific to CRoaring and may change between releases.
+// Like FrozenSizeInBytes, it works on a temporary copy unless ShrinkToFit was called first.
+func (rb *Bitmap64) WriteFrozen(b []byte) error {
+	rb.check("WriteFrozen")
+	c := rb.compact()
+	if c != rb {
+		defer c.Free()
+	}
+	if len(b) < int(C.roaring64_bitmap_frozen_size_in_bytes(c.cpointer)) {
+		return ErrBufferTooSmall
+	}
+	bchar := (*C.char)(unsafe.Pointer(&b[0]))
+	C.roaring64_bitmap_frozen_serialize(c.cpointer, bchar)
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(c)
+	return nil
+}
+
+// ReadFrozenView64 reads a frozen serialized version of the 64-bit bitmap
+// The result is a read-only view: call Clone to get a mutable copy.
+// The buffer is pinned for the lifetime of the view, or copied if it is not 64-byte aligned,
+// see ReadFrozenView64Mode. Before Go 1.21, which cannot pin memory, the buffer is always copied.
+func ReadFrozenView64(b []byte) (*ImmutableBitmap64, error) {
+	return ReadFrozenView64Mode(b, PinBuffer)
+}
+
+// ReadFrozenView64Mode reads a frozen serialized version of the 64-bit bitmap, with the buffer
+// either pinned or copied into C memory, see ViewMode.
+// The result is a read-only view: call Clone to get a mutable copy.
+func ReadFrozenView64Mode(b []byte, mode ViewMode) (*ImmutableBitmap64, error) {
+	if len(b) == 0 {
+		return nil, ErrEmpty
+	}
+	if err := checkFrozen64(b); err != nil {
+		return nil, err
+	}
+	bchar, buffer, release := viewMemory(b, 64, mode)
+	answer := newImmutableBitmap64(C.roaring64_bitmap_frozen_view(bchar, C.size_t(len(b))), buffer, release)
+	if answer == nil {
+		release()
+		return nil, ErrCorrupt
+	}
+	return answer, nil
+}
+
+// frozen64ARTNodeSizes are the sizes of the ART nodes in the frozen 64-bit format, indexed by node type
+// (leaf, node4, node16, node48 and node256)
+var frozen64ARTNodeSizes = [...]uint64{
+	0,
+	C.sizeof_gocroaring_art_leaf_t,
+	C.sizeof_gocroaring_art_node4_t,
+	C.sizeof_gocroaring_art_node16_t,
+	C.sizeof_gocroaring_art_node48_t,
+	C.sizeof_gocroaring_art_node256_t,
+}
+
+// checkFrozen64 checks that b is long enough for the sections announced by its frozen header:
+// roaring64_bitmap_frozen_view reads past the end of a short buffer instead of failing.
+func checkFrozen64(b []byte) error {
+	size := uint64(len(b))
+	var offset uint64
+	// reserve returns false if the next n bytes do not fit in b
+	reserve := func(n uint64) bool {
+		if n > size-offset {
+			return false
+		}
+		offset += n
+		return true
+	}
+	align := func(alignment uint64) bool {
+		return reserve((alignment - offset%alignment) % alignment)
+	}
+
+	// flags, container count, container sizes and the three zone sizes
+	if !reserve(1 + 8) {
+		return ErrCorrupt
+	}
+	containers := binary.LittleEndian.Uint64(b[1:])
+	if containers > size || !reserve(2*containers) {
+		return ErrCorrupt
+	}
+	zones := offset
+	if !reserve(3 * 8) {
+		return ErrCorrupt
+	}
+	// ART: root, node counts by type, then the nodes
+	if !align(8) {
+		return ErrCorrupt
+	}
+	art := offset
+	if !reserve(8+8*uint64(len(frozen64ARTNodeSizes))) || !align(8) {
+		return ErrCorrupt
+	}
+	for t := 1; t < len(frozen64ARTNodeSizes); t++ {
+		count := binary.LittleEndian.Uint64(b[art+8+8*uint64(t):])
+		if count > size || !reserve(count*frozen64ARTNodeSizes[t]) {
+			return ErrCorrupt
+		}
+	}
+	// one leaf per container
+	if binary.LittleEndian.Uint64(b[art+8+8:]) != containers {
+		return ErrCorrupt
+	}
+	// bitset, run and array zones
+	if !align(64) {
+		return ErrCorrupt
+	}
+	for i, alignment := range []uint64{1, 2, 2} {
+		if !align(alignment) || !reserve(binary.LittleEndian.Uint64(b[zones+8*uint64(i):])) {
+			return ErrCorrupt
+		}
+	}
+	return nil
+}
+
+// ToBitmap64 creates a new Bitmap64 holding the integers of the Bitmap, with high as their upper 32 bits
+// (pass 0 to keep the values unchanged).
+// This function may panic if the allocation failed.
+func (rb *Bitmap) ToBitmap64(high uint32) *Bitmap64 {
+	rb.check("ToBitmap64")
+	b := &Bitmap64{C.gocroaring_bitmap_to_64(rb.cpointer, C.uint32_t(high))}
+	runtime.KeepAlive(rb)
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer64(b)
+	return b
+}
+
+// ToBitmaps splits the Bitmap64 into 32-bit bitmaps keyed by the upper 32 bits of the integers they hold.
+// This function may panic if the allocation failed.
+func (rb *Bitmap64) ToBitmaps() map[uint32]*Bitmap {
+	rb.check("ToBitmaps")
+	answer := make(map[uint32]*Bitmap)
+	it := C.roaring64_iterator_create(rb.cpointer)
+	if it == nil {
+		panic("C code returned a null pointer.")
+	}
+	defer C.roaring64_iterator_free(it)
+	var buf [4096]uint64
+	var low [4096]uint32
+	for {
+		n := int(C.roaring64_iterator_read(it, (*C.uint64_t)(unsafe.Pointer(&buf[0])), C.uint64_t(len(buf))))
+		if n == 0 {
+			break
+		}
+		for start := 0; start < n; {
+			high := uint32(buf[start] >> 32)
+			end := start
+			for end < n && uint32(buf[end]>>32) == high {
+				low[end-start] = uint32(buf[end])
+				end++
+			}
+			b, ok := answer[high]
+			if !ok {
+				b = New()
+				answer[high] = b
+			}
+			C.roaring_bitmap_add_many(b.cpointer, C.size_t(end-start), (*C.uint32_t)(unsafe.Pointer(&low[0])))
+			runtime.KeepAlive(b)
+			start = end
+		}
+	}
+	runtime.KeepAlive(rb)
+	return answer
+}
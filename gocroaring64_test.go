@@ -0,0 +1,369 @@
+package gocroaring
+
+import (
+	"bytes"
+	"math/rand"
+	"os"
+	"runtime"
+	"testing"
+)
+
+func TestNew64WithVals(t *testing.T) {
+	vals := []uint64{1, 2, 3, 6, 7, 8, 20, 44444, 1 << 40, 1<<63 + 5}
+	rb := New64(vals...)
+	if int(rb.Cardinality()) != len(vals) {
+		t.Errorf("cardinality: expected %d, got %d", len(vals), rb.Cardinality())
+	}
+	for _, v := range vals {
+		if !rb.Contains(v) {
+			t.Errorf("expected %d from initialized values\n", v)
+		}
+	}
+	if rb.Contains(5) {
+		t.Error("didn't expect to contain 5")
+	}
+	if rb.Minimum() != 1 {
+		t.Errorf("minimum: expected 1, got %d", rb.Minimum())
+	}
+	if rb.Maximum() != 1<<63+5 {
+		t.Errorf("maximum: expected %d, got %d", uint64(1<<63+5), rb.Maximum())
+	}
+}
+
+func TestBitmap64AddRemove(t *testing.T) {
+	rb := New64()
+	if !rb.IsEmpty() {
+		t.Error("expected an empty bitmap")
+	}
+	rb.Add(1<<32, 1<<33)
+	rb.AddRange(1<<40, 1<<40+10)
+	if rb.Cardinality() != 12 {
+		t.Errorf("cardinality: expected %d, got %d", 12, rb.Cardinality())
+	}
+	if !rb.ContainsRange(1<<40, 1<<40+10) {
+		t.Error("expected to contain the added range")
+	}
+	rb.Remove(1 << 32)
+	rb.RemoveRange(1<<40, 1<<40+5)
+	if rb.Contains(1<<32) || rb.Contains(1<<40) {
+		t.Error("didn't expect removed values")
+	}
+	if rb.Cardinality() != 6 {
+		t.Errorf("cardinality: expected %d, got %d", 6, rb.Cardinality())
+	}
+	if rb.String() != "{8589934592,1099511627781,1099511627782,1099511627783,1099511627784,1099511627785}" {
+		t.Errorf("bad string %s", rb.String())
+	}
+	rb.Clear()
+	if !rb.IsEmpty() {
+		t.Error("expected an empty bitmap after Clear")
+	}
+}
+
+func TestBitmap64RankSelect(t *testing.T) {
+	rb := New64(10, 1<<35, 1<<50)
+	if rb.Rank(1<<35) != 2 {
+		t.Errorf("rank: expected 2, got %d", rb.Rank(1<<35))
+	}
+	v, err := rb.Select(2)
+	if err != nil || v != 1<<50 {
+		t.Errorf("select: expected %d, got %d (%v)", uint64(1<<50), v, err)
+	}
+	if _, err := rb.Select(3); err == nil {
+		t.Error("expected an error when selecting past the end")
+	}
+}
+
+func TestBitmap64SetOperations(t *testing.T) {
+	base := uint64(1) << 40
+	rb1 := New64(base+1, base+2, base+3, 7)
+	rb2 := New64(base+2, base+3, base+4)
+
+	if rb1.AndCardinality(rb2) != 2 || rb1.OrCardinality(rb2) != 5 ||
+		rb1.XorCardinality(rb2) != 3 || rb1.AndNotCardinality(rb2) != 2 {
+		t.Error("bad cardinality helpers")
+	}
+	if !rb1.Intersect(rb2) {
+		t.Error("expected the bitmaps to intersect")
+	}
+	if !And64(rb1, rb2).Equals(New64(base+2, base+3)) {
+		t.Error("bad And64")
+	}
+	if !Or64(rb1, rb2).Equals(New64(7, base+1, base+2, base+3, base+4)) {
+		t.Error("bad Or64")
+	}
+	if !Xor64(rb1, rb2).Equals(New64(7, base+1, base+4)) {
+		t.Error("bad Xor64")
+	}
+	if !AndNot64(rb1, rb2).Equals(New64(7, base+1)) {
+		t.Error("bad AndNot64")
+	}
+
+	c := rb1.Clone()
+	c.And(rb2)
+	if c.Cardinality() != 2 {
+		t.Errorf("And: expected 2, got %d", c.Cardinality())
+	}
+	c = rb1.Clone()
+	c.Or(rb2)
+	if c.Cardinality() != 5 {
+		t.Errorf("Or: expected 5, got %d", c.Cardinality())
+	}
+	c = rb1.Clone()
+	c.Xor(rb2)
+	if c.Cardinality() != 3 {
+		t.Errorf("Xor: expected 3, got %d", c.Cardinality())
+	}
+	c = rb1.Clone()
+	c.AndNot(rb2)
+	if c.Cardinality() != 2 {
+		t.Errorf("AndNot: expected 2, got %d", c.Cardinality())
+	}
+	if rb1.Cardinality() != 4 {
+		t.Error("Clone should not share state with the original")
+	}
+	if rb1.Equals(New(7)) {
+		t.Error("a Bitmap64 should not equal a Bitmap")
+	}
+}
+
+func TestBitmap64Flip(t *testing.T) {
+	base := uint64(1) << 36
+	rb := New64(base, base+2)
+	flipped := Flip64(rb, base, base+4)
+	if !flipped.Equals(New64(base+1, base+3)) {
+		t.Errorf("bad Flip64: %s", flipped)
+	}
+	rb.Flip(base, base+4)
+	if !rb.Equals(flipped) {
+		t.Errorf("bad Flip: %s", rb)
+	}
+}
+
+func TestBitmap64Free(t *testing.T) {
+	rb := New64()
+	rb.AddRange(0, 1<<20)
+	rb.RunOptimize()
+	c := rb.Clone()
+	rb.Free()
+	if c.Cardinality() != 1<<20 {
+		t.Errorf("cardinality: expected %d, got %d", 1<<20, c.Cardinality())
+	}
+	c.Free()
+}
+
+// The files in testdata/bitmap64_*.bin follow the 64-bit extension of the
+// portable format (https://github.com/RoaringBitmap/RoaringFormatSpec), which is
+// what the Go roaring64 package and Java's Roaring64NavigableMap produce. They
+// are generated by testdata/gen_bitmap64.go with the Go roaring64 package.
+func TestBitmap64Golden(t *testing.T) {
+	high := uint64(1) << 63
+	runs := New64()
+	runs.AddRange(1<<32, 1<<32+1000)
+	runs.AddRange(high|3<<16|10, high|3<<16|15)
+	runs.AddRange(high|3<<16|100, high|3<<16|110)
+	runs.RunOptimize()
+
+	for _, tc := range []struct {
+		file string
+		rb   *Bitmap64
+	}{
+		{"testdata/bitmap64_empty.bin", New64()},
+		{"testdata/bitmap64_array.bin", New64(1, 2, 3, 1<<32+5, 1<<33+7)},
+		{"testdata/bitmap64_run.bin", runs},
+	} {
+		golden, err := os.ReadFile(tc.file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tc.rb.SerializedSizeInBytes() != len(golden) {
+			t.Errorf("%s: expected size %d, got %d", tc.file, len(golden), tc.rb.SerializedSizeInBytes())
+			continue
+		}
+		buf := make([]byte, tc.rb.SerializedSizeInBytes())
+		if err := tc.rb.Write(buf); err != nil {
+			t.Errorf("%s: Write failed %v", tc.file, err)
+		}
+		if !bytes.Equal(buf, golden) {
+			t.Errorf("%s: serialized bytes differ from the golden file\n got %x\nwant %x", tc.file, buf, golden)
+		}
+		if SerializedSize64(golden) != len(golden) {
+			t.Errorf("%s: expected SerializedSize64 %d, got %d", tc.file, len(golden), SerializedSize64(golden))
+		}
+		newrb, err := Read64(golden)
+		if err != nil {
+			t.Errorf("%s: Read64 failed %v", tc.file, err)
+		} else if !tc.rb.Equals(newrb) {
+			t.Errorf("%s: bad read %s", tc.file, newrb)
+		}
+	}
+}
+
+func TestBitmap64WriteRead(t *testing.T) {
+	rb := New64()
+	for i := uint64(0); i < 100000; i++ {
+		rb.Add(i * 0x100000007)
+	}
+	buf := make([]byte, rb.SerializedSizeInBytes()-1)
+	if err := rb.Write(buf); err == nil {
+		t.Error("expected an error when the buffer is too small")
+	}
+	buf = make([]byte, rb.SerializedSizeInBytes())
+	if err := rb.Write(buf); err != nil {
+		t.Error("Write failed", err)
+	}
+	newrb, err := Read64(buf)
+	if err != nil {
+		t.Error("Read64 failed", err)
+	}
+	if !rb.Equals(newrb) {
+		t.Error("Bad read")
+	}
+	if _, err := Read64(buf[:len(buf)/2]); err == nil {
+		t.Error("expected an error on truncated input")
+	}
+	if _, err := Read64(nil); err == nil {
+		t.Error("expected an error on empty input")
+	}
+	if SerializedSize64(buf[:len(buf)/2]) != 0 {
+		t.Error("expected SerializedSize64 to reject truncated input")
+	}
+}
+
+// artBitmap64 returns a bitmap whose ART holds every node type: the 4 values of the
+// third key byte fan out to 3, 10, 40 and 256 values of the last key byte.
+func artBitmap64() *Bitmap64 {
+	rb := New64()
+	for i, children := range []uint64{3, 10, 40, 256} {
+		for c := uint64(0); c < children; c++ {
+			rb.Add(uint64(i)<<32 | c<<16)
+		}
+	}
+	return rb
+}
+
+func TestBitmap64WriteFrozen(t *testing.T) {
+	rb := New64()
+	for i := 0; i < 100000; i++ {
+		rb.Add(uint64(rand.Int63n(1 << 40)))
+	}
+	rb.AddRange(1<<50, 1<<50+100000)
+	rb.RunOptimize()
+
+	// the size query and the write must not change the layout of the bitmap
+	size := rb.FrozenSizeInBytes()
+	if err := rb.WriteFrozen(AlignedBuffer(size)); err != nil {
+		t.Error("WriteFrozen failed", err)
+	}
+	if rb.ShrinkToFit() == 0 {
+		t.Error("FrozenSizeInBytes or WriteFrozen shrank the bitmap")
+	}
+	if rb.FrozenSizeInBytes() != size {
+		t.Error("the frozen size depends on the layout")
+	}
+
+	buf := AlignedBuffer(rb.FrozenSizeInBytes())
+	if err := rb.WriteFrozen(buf[:len(buf)-1]); err == nil {
+		t.Error("expected an error when the buffer is too small")
+	}
+	if err := rb.WriteFrozen(buf); err != nil {
+		t.Error("WriteFrozen failed", err)
+	}
+	view, err := ReadFrozenView64(buf)
+	if err != nil {
+		t.Fatal("ReadFrozenView64 failed", err)
+	}
+	runtime.GC() // the view must keep the buffer alive
+	if !rb.Equals(view) {
+		t.Error("Bad read")
+	}
+	if view.Cardinality() != rb.Cardinality() || !view.Contains(1<<50+99999) {
+		t.Error("bad frozen view")
+	}
+	c := view.Clone()
+	c.Add(1 << 60)
+	if !c.Contains(1<<60) || view.Contains(1<<60) {
+		t.Error("a clone of a frozen view should be mutable and independent")
+	}
+	view.Free()
+
+	misaligned := AlignedBuffer(len(buf) + 1)[1:]
+	copy(misaligned, buf)
+	view, err = ReadFrozenView64(misaligned)
+	if err != nil {
+		t.Fatal("ReadFrozenView64 failed on a misaligned buffer", err)
+	}
+	if !rb.Equals(view) {
+		t.Error("bad read of a misaligned buffer")
+	}
+	view.Free()
+
+	// the node sizes checkFrozen64 assumes must match CRoaring's
+	rb = artBitmap64()
+	buf = AlignedBuffer(rb.FrozenSizeInBytes())
+	rb.WriteFrozen(buf)
+	view, err = ReadFrozenView64(buf)
+	if err != nil {
+		t.Fatal("ReadFrozenView64 failed on every ART node type", err)
+	}
+	if !rb.Equals(view) {
+		t.Error("bad read of every ART node type")
+	}
+	view.Free()
+	if _, err := ReadFrozenView64(nil); err == nil {
+		t.Error("expected an error on empty input")
+	}
+}
+
+func TestBitmapToBitmap64(t *testing.T) {
+	rb := New(1, 2, 3, 1<<20, 1<<31, 0xFFFFFFFF)
+	rb.AddRange(1000, 200000)
+
+	same := rb.ToBitmap64(0)
+	if same.Cardinality() != rb.Cardinality() {
+		t.Errorf("cardinality: expected %d, got %d", rb.Cardinality(), same.Cardinality())
+	}
+	if rb.Cardinality() != 199006 {
+		t.Error("ToBitmap64 should not modify the source bitmap")
+	}
+	prefixed := rb.ToBitmap64(7)
+	for _, v := range rb.ToArray() {
+		if !same.Contains(uint64(v)) {
+			t.Errorf("expected to contain %d", v)
+		}
+		if !prefixed.Contains(7<<32 | uint64(v)) {
+			t.Errorf("expected to contain %d", 7<<32|uint64(v))
+		}
+	}
+	if prefixed.Minimum() != 7<<32|1 || prefixed.Maximum() != 7<<32|0xFFFFFFFF {
+		t.Errorf("bad bounds %d %d", prefixed.Minimum(), prefixed.Maximum())
+	}
+	if New().ToBitmap64(3).Cardinality() != 0 {
+		t.Error("expected an empty bitmap")
+	}
+}
+
+func TestBitmap64ToBitmaps(t *testing.T) {
+	rb := New64(1, 2, 1<<32|5, 1<<63|1<<32|9)
+	rb.AddRange(3<<32, 3<<32+100000)
+	parts := rb.ToBitmaps()
+	if len(parts) != 4 {
+		t.Fatalf("expected 4 parts, got %d", len(parts))
+	}
+	if !parts[0].Equals(New(1, 2)) || !parts[1].Equals(New(5)) || !parts[1<<31|1].Equals(New(9)) {
+		t.Error("bad split")
+	}
+	if parts[3].Cardinality() != 100000 || !parts[3].ContainsRange(0, 100000) {
+		t.Error("bad split of the range")
+	}
+	back := New64()
+	for high, b := range parts {
+		back.Or(b.ToBitmap64(high))
+	}
+	if !back.Equals(rb) {
+		t.Error("round trip through ToBitmaps and ToBitmap64 failed")
+	}
+	if len(New64().ToBitmaps()) != 0 {
+		t.Error("expected no parts for an empty bitmap")
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"reflect"
 	"runtime"
+	"strings"
 	"testing"
 )
 
@@ -363,3 +364,288 @@ func TestAssign(t *testing.T) {
 		t.Error("should equal")
 	}
 }
+
+func TestManyIterator(t *testing.T) {
+	rb := New()
+	for i := 0; i < 100000; i++ {
+		rb.Add(uint32(rand.Intn(10000000)))
+	}
+	rb.AddRange(20000000, 20070000)
+	expected := rb.ToArray()
+	for _, size := range []int{1, 2, 7, 4096, 1000000} {
+		buf := make([]uint32, size)
+		var got []uint32
+		i := rb.ManyIterator()
+		for n := i.NextMany(buf); n > 0; n = i.NextMany(buf) {
+			got = append(got, buf[:n]...)
+		}
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("buffer of size %d: expected %d values, got %d", size, len(expected), len(got))
+		}
+		if i.NextMany(buf) != 0 {
+			t.Error("expected the iterator to be drained")
+		}
+	}
+	if New().ManyIterator().NextMany(make([]uint32, 10)) != 0 {
+		t.Error("expected no values from an empty bitmap")
+	}
+	if rb.ManyIterator().NextMany(nil) != 0 {
+		t.Error("expected no values with an empty buffer")
+	}
+
+	// Next and NextMany can be mixed on the same iterator
+	it := newIntIterator(rb)
+	buf := make([]uint32, 3)
+	var got []uint32
+	for it.HasNext() {
+		got = append(got, it.Next())
+		n := it.NextMany(buf)
+		got = append(got, buf[:n]...)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("mixed iteration: expected %d values, got %d", len(expected), len(got))
+	}
+}
+
+func TestSeekableIterator(t *testing.T) {
+	rb := New(1, 2, 3, 70000, 70001)
+	rb.AddRange(1<<20, 1<<20+10)
+	values := rb.ToArray()
+
+	i := rb.SeekableIterator()
+	if i.HasPrevious() {
+		t.Error("expected nothing before the first value")
+	}
+	if i.PeekNext() != 1 || i.Next() != 1 || i.Next() != 2 {
+		t.Error("bad forward iteration")
+	}
+	if !i.HasPrevious() || i.Previous() != 2 || i.Previous() != 1 || i.HasPrevious() {
+		t.Error("bad backward iteration")
+	}
+	if i.Next() != 1 {
+		t.Error("Next should return the value Previous returned")
+	}
+
+	i.AdvanceIfNeeded(4)
+	if i.PeekNext() != 70000 {
+		t.Errorf("AdvanceIfNeeded: expected 70000, got %d", i.PeekNext())
+	}
+	i.AdvanceIfNeeded(2)
+	if i.PeekNext() != 70000 {
+		t.Error("AdvanceIfNeeded should never move backward")
+	}
+	i.AdvanceIfNeeded(1<<20 + 5)
+	if i.Next() != 1<<20+5 {
+		t.Error("bad AdvanceIfNeeded into a run")
+	}
+	i.AdvanceIfNeeded(1 << 30)
+	if i.HasNext() {
+		t.Error("expected the iterator to be drained")
+	}
+	i.AdvanceIfNeeded(1 << 31)
+	if i.HasNext() || !i.HasPrevious() || i.Previous() != values[len(values)-1] {
+		t.Error("bad iteration from past the last value")
+	}
+
+	for start := 0; start <= len(values); start++ {
+		for n := 0; n <= len(values)+2; n++ {
+			i := rb.SeekableIterator()
+			if got := i.Skip(uint32(start)); got != uint32(start) {
+				t.Fatalf("Skip(%d): expected %d, got %d", start, start, got)
+			}
+			expected := n
+			if expected > start {
+				expected = start
+			}
+			if got := i.SkipBackward(uint32(n)); got != uint32(expected) {
+				t.Errorf("SkipBackward(%d) from %d: expected %d, got %d", n, start, expected, got)
+			}
+			if start-expected == len(values) {
+				if i.HasNext() {
+					t.Errorf("SkipBackward(%d) from %d: expected to stay past the last value", n, start)
+				}
+			} else if !i.HasNext() || i.PeekNext() != values[start-expected] {
+				t.Errorf("SkipBackward(%d) from %d: bad position", n, start)
+			}
+		}
+	}
+	i = rb.SeekableIterator()
+	if i.Skip(100) != uint32(len(values)) || i.HasNext() {
+		t.Error("Skip past the end should drain the iterator")
+	}
+
+	empty := New().SeekableIterator()
+	if empty.HasNext() || empty.HasPrevious() || empty.Skip(1) != 0 || empty.SkipBackward(1) != 0 {
+		t.Error("expected nothing to iterate over")
+	}
+}
+
+// merge join between two bitmaps by leapfrogging
+func TestSeekableIteratorLeapfrog(t *testing.T) {
+	rb1 := New()
+	rb2 := New()
+	for i := 0; i < 10000; i++ {
+		rb1.Add(uint32(rand.Intn(1000000)))
+		rb2.Add(uint32(rand.Intn(1000000)))
+	}
+	var got []uint32
+	i1, i2 := rb1.SeekableIterator(), rb2.SeekableIterator()
+	for i1.HasNext() && i2.HasNext() {
+		v1, v2 := i1.PeekNext(), i2.PeekNext()
+		switch {
+		case v1 < v2:
+			i1.AdvanceIfNeeded(v2)
+		case v2 < v1:
+			i2.AdvanceIfNeeded(v1)
+		default:
+			got = append(got, v1)
+			i1.Next()
+			i2.Next()
+		}
+	}
+	expected := And(rb1, rb2).ToArray()
+	if !reflect.DeepEqual(expected, got) && len(expected)+len(got) > 0 {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestReverseIterator(t *testing.T) {
+	rb := New()
+	for i := 0; i < 100000; i++ {
+		rb.Add(uint32(rand.Intn(10000000)))
+	}
+	rb.AddRange(20000000, 20070000)
+	rb.Add(0xFFFFFFFF)
+	values := rb.ToArray()
+	expected := make([]uint32, len(values))
+	for i, v := range values {
+		expected[len(values)-1-i] = v
+	}
+
+	var got []uint32
+	i := rb.ReverseIterator()
+	for i.HasNext() {
+		got = append(got, i.Next())
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %d values, got %d", len(expected), len(got))
+	}
+
+	buf := make([]uint32, 1000)
+	got = got[:0]
+	m := rb.ReverseManyIterator()
+	for n := m.NextMany(buf); n > 0; n = m.NextMany(buf) {
+		got = append(got, buf[:n]...)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("NextMany: expected %d values, got %d", len(expected), len(got))
+	}
+
+	if New().ReverseIterator().HasNext() {
+		t.Error("expected nothing to iterate over")
+	}
+}
+
+func TestTopK(t *testing.T) {
+	rb := New(5, 1, 70000, 3, 1<<31)
+	if got := rb.TopK(3); !reflect.DeepEqual(got, []uint32{1 << 31, 70000, 5}) {
+		t.Errorf("bad TopK(3): %v", got)
+	}
+	if got := rb.TopK(10); !reflect.DeepEqual(got, []uint32{1 << 31, 70000, 5, 3, 1}) {
+		t.Errorf("bad TopK(10): %v", got)
+	}
+	if len(rb.TopK(0)) != 0 || len(rb.TopK(-1)) != 0 || len(New().TopK(3)) != 0 {
+		t.Error("expected no values")
+	}
+}
+
+func TestIterate(t *testing.T) {
+	rb := New()
+	for i := 0; i < 10000; i++ {
+		rb.Add(uint32(rand.Intn(10000000)))
+	}
+	rb.AddRange(20000000, 20070000)
+	var got []uint32
+	rb.Iterate(func(x uint32) bool {
+		got = append(got, x)
+		return true
+	})
+	if !reflect.DeepEqual(rb.ToArray(), got) {
+		t.Errorf("expected %d values, got %d", rb.Cardinality(), len(got))
+	}
+
+	count := 0
+	rb.Iterate(func(x uint32) bool {
+		count++
+		return count < 10
+	})
+	if count != 10 {
+		t.Errorf("expected to stop after 10 values, got %d", count)
+	}
+	New().Iterate(func(x uint32) bool {
+		t.Error("expected nothing to iterate over")
+		return true
+	})
+}
+
+// expectPanic checks that f panics with a message mentioning op
+func expectPanic(t *testing.T, op string, f func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Errorf("%s: expected a panic", op)
+		} else if msg, ok := r.(string); !ok || !strings.Contains(msg, op) {
+			t.Errorf("%s: the panic does not name the operation: %v", op, r)
+		}
+	}()
+	f()
+}
+
+func TestUseAfterFree(t *testing.T) {
+	rb := New(1, 2, 3)
+	rb.Free()
+	rb.Free() // safe
+	expectPanic(t, "Contains", func() { rb.Contains(1) })
+	expectPanic(t, "Add", func() { rb.Add(4) })
+	expectPanic(t, "Cardinality", func() { rb.Cardinality() })
+	expectPanic(t, "Iterator", func() { rb.Iterator() })
+	expectPanic(t, "Write", func() { rb.Write(make([]byte, 100)) })
+
+	other := New(5)
+	expectPanic(t, "Or", func() { Or(other, rb) })
+	expectPanic(t, "AndCardinality", func() { other.AndCardinality(rb) })
+	expectPanic(t, "FastOr", func() { FastOr(other, rb) })
+	if !other.Equals(New(5)) {
+		t.Error("the operand was modified")
+	}
+
+	buf, err := other.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	view, err := ReadPortableView(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	view.Close()
+	view.Free()
+	expectPanic(t, "Maximum", func() { view.Maximum() })
+	expectPanic(t, "And", func() { And(other, view) })
+	expectPanic(t, "Iterator", func() { view.Iterator() })
+	expectPanic(t, "ManyIterator", func() { view.ManyIterator() })
+	expectPanic(t, "SeekableIterator", func() { view.SeekableIterator() })
+	expectPanic(t, "ReverseIterator", func() { view.ReverseIterator() })
+	expectPanic(t, "ReverseManyIterator", func() { view.ReverseManyIterator() })
+
+	sb := NewSharedBitmap(New(1, 2, 3))
+	sb.Release()
+	expectPanic(t, "Iterator", func() { sb.Iterator() })
+	expectPanic(t, "ReverseIterator", func() { sb.ReverseIterator() })
+
+	rb64 := New64(1, 2, 3)
+	rb64.Free()
+	rb64.Free()
+	expectPanic(t, "Rank", func() { rb64.Rank(2) })
+	expectPanic(t, "Xor64", func() { Xor64(New64(), rb64) })
+}
@@ -0,0 +1,508 @@
+package gocroaring
+
+/*
+#include "roaring.h"
+
+*/
+import "C"
+import (
+	"io"
+	"runtime"
+	"unsafe"
+)
+
+// ReadOnlyBitmap gives read access to a Bitmap or an ImmutableBitmap.
+// Operations combining two bitmaps, such as And or Intersect, accept either kind
+// for their second operand.
+type ReadOnlyBitmap interface {
+	Contains(x uint32) bool
+	ContainsRange(x, y uint64) bool
+	Cardinality() uint64
+	IsEmpty() bool
+	Minimum() uint32
+	Maximum() uint32
+	Rank(x uint32) uint64
+	Select(rank uint32) (uint32, error)
+	Equals(o interface{}) bool
+	Clone() *Bitmap
+	ToArray() []uint32
+	Iterator() IntIterable
+	SerializedSizeInBytes() int
+	Write(b []byte) error
+	String() string
+
+	cbitmap(op string) *C.struct_roaring_bitmap_s
+}
+
+// cbitmap returns the C bitmap, or panics naming the operation if the bitmap was freed
+func (rb *Bitmap) cbitmap(op string) *C.struct_roaring_bitmap_s {
+	rb.check(op)
+	return rb.cpointer
+}
+
+// readOnlyBitmap holds the queries of Bitmap, for the types that must not be modified
+type readOnlyBitmap struct {
+	rb Bitmap
+}
+
+// ImmutableBitmap is a read-only bitmap, such as a view over serialized data.
+// It offers the queries of Bitmap, and can be the operand of And, Or, Xor, AndNot
+// and Flip, but it cannot be modified: call Clone to get a mutable copy.
+type ImmutableBitmap struct {
+	readOnlyBitmap
+	buffer  *byte        // keeps the Go buffer backing the view alive
+	release func() error // called once the view is freed, e.g. to unmap a file
+}
+
+// newImmutableBitmap wraps a C bitmap, or returns nil if cpointer is nil
+func newImmutableBitmap(cpointer *C.struct_roaring_bitmap_s, buffer *byte, release func() error) *ImmutableBitmap {
+	if cpointer == nil {
+		return nil
+	}
+	answer := &ImmutableBitmap{readOnlyBitmap{Bitmap{cpointer: cpointer}}, buffer, release}
+	track(unsafe.Pointer(cpointer), kindImmutableBitmap)
+	runtime.SetFinalizer(answer, finalizeImmutableBitmap)
+	return answer
+}
+
+func finalizeImmutableBitmap(ib *ImmutableBitmap) {
+	untrack(unsafe.Pointer(ib.rb.cpointer), true)
+	ib.Close()
+}
+
+func (ib *readOnlyBitmap) cbitmap(op string) *C.struct_roaring_bitmap_s {
+	return ib.rb.cbitmap(op)
+}
+
+// Close frees the bitmap and releases the memory backing it, it implements io.Closer
+func (ib *ImmutableBitmap) Close() error {
+	runtime.SetFinalizer(ib, nil)
+	if ib.rb.cpointer == nil {
+		return nil
+	}
+	untrack(unsafe.Pointer(ib.rb.cpointer), false)
+	C.roaring_bitmap_free(ib.rb.cpointer)
+	ib.rb.cpointer = nil
+	ib.buffer = nil
+	if ib.release != nil {
+		return ib.release()
+	}
+	return nil
+}
+
+// Free is the same as Close
+func (ib *ImmutableBitmap) Free() {
+	ib.Close()
+}
+
+// Clone creates a mutable copy of the bitmap
+// This function may panic if the allocation failed.
+func (ib *readOnlyBitmap) Clone() *Bitmap {
+	return ib.rb.Clone()
+}
+
+// Contains returns true if the integer is contained in the bitmap
+func (ib *readOnlyBitmap) Contains(x uint32) bool {
+	return ib.rb.Contains(x)
+}
+
+// ContainsRange returns true if the integers in the range [x, y) are contained in the bitmap
+func (ib *readOnlyBitmap) ContainsRange(x, y uint64) bool {
+	return ib.rb.ContainsRange(x, y)
+}
+
+// Cardinality returns the number of integers contained in the bitmap
+func (ib *readOnlyBitmap) Cardinality() uint64 {
+	return ib.rb.Cardinality()
+}
+
+// GetCardinality returns the number of integers contained in the bitmap
+func (ib *readOnlyBitmap) GetCardinality() uint64 {
+	return ib.rb.Cardinality()
+}
+
+// IsEmpty returns true if the bitmap is empty (it is faster than doing (Cardinality() == 0))
+func (ib *readOnlyBitmap) IsEmpty() bool {
+	return ib.rb.IsEmpty()
+}
+
+// Maximum returns the largest of the integers contained in the bitmap assuming that it is not empty
+func (ib *readOnlyBitmap) Maximum() uint32 {
+	return ib.rb.Maximum()
+}
+
+// Minimum returns the smallest of the integers contained in the bitmap assuming that it is not empty
+func (ib *readOnlyBitmap) Minimum() uint32 {
+	return ib.rb.Minimum()
+}
+
+// MaximumOK returns the largest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (ib *readOnlyBitmap) MaximumOK() (x uint32, ok bool) {
+	return ib.rb.MaximumOK()
+}
+
+// MinimumOK returns the smallest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (ib *readOnlyBitmap) MinimumOK() (x uint32, ok bool) {
+	return ib.rb.MinimumOK()
+}
+
+// Rank returns the number of values smaller or equal to x
+func (ib *readOnlyBitmap) Rank(x uint32) uint64 {
+	return ib.rb.Rank(x)
+}
+
+// Select returns the element having the designated rank, if it exists
+func (ib *readOnlyBitmap) Select(rank uint32) (uint32, error) {
+	return ib.rb.Select(rank)
+}
+
+// Equals returns true if the two bitmaps contain the same integers
+func (ib *readOnlyBitmap) Equals(o interface{}) bool {
+	return ib.rb.Equals(o)
+}
+
+// Intersect checks whether the two bitmaps intersect
+func (ib *readOnlyBitmap) Intersect(x2 ReadOnlyBitmap) bool {
+	return ib.rb.Intersect(x2)
+}
+
+// JaccardIndex computes the Jaccard index between two bitmaps
+func (ib *readOnlyBitmap) JaccardIndex(x2 ReadOnlyBitmap) float64 {
+	return ib.rb.JaccardIndex(x2)
+}
+
+// AndCardinality computes the size of the intersection between two bitmaps
+func (ib *readOnlyBitmap) AndCardinality(x2 ReadOnlyBitmap) uint64 {
+	return ib.rb.AndCardinality(x2)
+}
+
+// XorCardinality computes the size of the symmetric difference between two bitmaps
+func (ib *readOnlyBitmap) XorCardinality(x2 ReadOnlyBitmap) uint64 {
+	return ib.rb.XorCardinality(x2)
+}
+
+// OrCardinality computes the size of the union between two bitmaps
+func (ib *readOnlyBitmap) OrCardinality(x2 ReadOnlyBitmap) uint64 {
+	return ib.rb.OrCardinality(x2)
+}
+
+// AndNotCardinality computes the size of the difference between two bitmaps
+func (ib *readOnlyBitmap) AndNotCardinality(x2 ReadOnlyBitmap) uint64 {
+	return ib.rb.AndNotCardinality(x2)
+}
+
+// ToArray creates a new slice containing all of the integers stored in the bitmap in sorted order
+func (ib *readOnlyBitmap) ToArray() []uint32 {
+	return ib.rb.ToArray()
+}
+
+// String creates a string representation of the bitmap
+func (ib *readOnlyBitmap) String() string {
+	return ib.rb.String()
+}
+
+// Iterator creates a new IntIterable to iterate over the integers contained in the bitmap, in sorted order
+func (ib *readOnlyBitmap) Iterator() IntIterable {
+	ib.rb.check("Iterator")
+	return newIntIterator(&ib.rb)
+}
+
+// ManyIterator creates a new ManyIntIterable to iterate over the integers contained in the bitmap, in sorted order
+func (ib *readOnlyBitmap) ManyIterator() ManyIntIterable {
+	ib.rb.check("ManyIterator")
+	return newIntIterator(&ib.rb)
+}
+
+// SeekableIterator creates a new SeekableIntIterable positioned before the smallest integer contained in the bitmap
+func (ib *readOnlyBitmap) SeekableIterator() SeekableIntIterable {
+	ib.rb.check("SeekableIterator")
+	return newIntIterator(&ib.rb)
+}
+
+// ReverseIterator creates a new IntIterable to iterate over the integers contained in the bitmap, in decreasing order
+func (ib *readOnlyBitmap) ReverseIterator() IntIterable {
+	ib.rb.check("ReverseIterator")
+	return newReverseIntIterator(&ib.rb)
+}
+
+// ReverseManyIterator creates a new ManyIntIterable to iterate over the integers contained in the bitmap, in decreasing order
+func (ib *readOnlyBitmap) ReverseManyIterator() ManyIntIterable {
+	ib.rb.check("ReverseManyIterator")
+	return newReverseIntIterator(&ib.rb)
+}
+
+// TopK returns the (at most) k largest integers contained in the bitmap, in decreasing order
+func (ib *readOnlyBitmap) TopK(k int) []uint32 {
+	return ib.rb.TopK(k)
+}
+
+// Iterate calls f on the integers contained in the bitmap, in sorted order, until f returns false.
+func (ib *readOnlyBitmap) Iterate(f func(x uint32) bool) {
+	ib.rb.Iterate(f)
+}
+
+// IntervalCount returns the number of maximal runs of consecutive integers contained in the bitmap
+func (ib *readOnlyBitmap) IntervalCount() int {
+	return ib.rb.IntervalCount()
+}
+
+// Intervals returns the maximal runs of consecutive integers contained in the bitmap, in sorted order.
+func (ib *readOnlyBitmap) Intervals() []Interval {
+	return ib.rb.Intervals()
+}
+
+// SerializedSizeInBytes computes the serialized size in bytes of the bitmap.
+func (ib *readOnlyBitmap) SerializedSizeInBytes() int {
+	return ib.rb.SerializedSizeInBytes()
+}
+
+// Write writes a serialized version of this bitmap to stream (you should have enough space)
+func (ib *readOnlyBitmap) Write(b []byte) error {
+	return ib.rb.Write(b)
+}
+
+// AppendTo appends a serialized version of this bitmap to b and returns the extended slice
+func (ib *readOnlyBitmap) AppendTo(b []byte) []byte {
+	return ib.rb.AppendTo(b)
+}
+
+// WriteTo writes a serialized version of this bitmap to the stream, it implements io.WriterTo
+func (ib *readOnlyBitmap) WriteTo(w io.Writer) (int64, error) {
+	return ib.rb.WriteTo(w)
+}
+
+// MarshalBinary returns the portable serialization of the bitmap, it implements encoding.BinaryMarshaler
+func (ib *readOnlyBitmap) MarshalBinary() ([]byte, error) {
+	return ib.rb.MarshalBinary()
+}
+
+// MarshalJSON encodes the bitmap as an array of integers, it implements json.Marshaler
+func (ib *readOnlyBitmap) MarshalJSON() ([]byte, error) {
+	return ib.rb.MarshalJSON()
+}
+
+// FrozenSizeInBytes computes the frozen serialized size in bytes
+func (ib *readOnlyBitmap) FrozenSizeInBytes() int {
+	return ib.rb.FrozenSizeInBytes()
+}
+
+// WriteFrozen writes a serialized version of bitmap to the stream in the Frozen format
+func (ib *readOnlyBitmap) WriteFrozen(b []byte) error {
+	return ib.rb.WriteFrozen(b)
+}
+
+// NativeSizeInBytes computes the size in bytes of the bitmap in the CRoaring native format
+func (ib *readOnlyBitmap) NativeSizeInBytes() int {
+	return ib.rb.NativeSizeInBytes()
+}
+
+// WriteNative writes a serialized version of this bitmap to stream in the CRoaring native format
+func (ib *readOnlyBitmap) WriteNative(b []byte) error {
+	return ib.rb.WriteNative(b)
+}
+
+// Stats returns some statistics about the roaring bitmap.
+func (ib *readOnlyBitmap) Stats() map[string]uint64 {
+	return ib.rb.Stats()
+}
+
+// StatsStruct - same as Stats but returns typed struct.
+func (ib *readOnlyBitmap) StatsStruct() Statistics {
+	return ib.rb.StatsStruct()
+}
+
+// ReadOnlyBitmap64 gives read access to a Bitmap64 or an ImmutableBitmap64.
+type ReadOnlyBitmap64 interface {
+	Contains(x uint64) bool
+	ContainsRange(x, y uint64) bool
+	Cardinality() uint64
+	IsEmpty() bool
+	Minimum() uint64
+	Maximum() uint64
+	Rank(x uint64) uint64
+	Select(rank uint64) (uint64, error)
+	Equals(o interface{}) bool
+	Clone() *Bitmap64
+	ToArray() []uint64
+	SerializedSizeInBytes() int
+	Write(b []byte) error
+	String() string
+
+	cbitmap64(op string) *C.roaring64_bitmap_t
+}
+
+// cbitmap64 returns the C bitmap, or panics naming the operation if the bitmap was freed
+func (rb *Bitmap64) cbitmap64(op string) *C.roaring64_bitmap_t {
+	rb.check(op)
+	return rb.cpointer
+}
+
+// ImmutableBitmap64 is a read-only 64-bit bitmap, such as a view over serialized data.
+// Call Clone to get a mutable copy.
+type ImmutableBitmap64 struct {
+	rb      Bitmap64
+	buffer  *byte        // keeps the Go buffer backing the view alive
+	release func() error // called once the view is freed
+}
+
+// newImmutableBitmap64 wraps a C bitmap, or returns nil if cpointer is nil
+func newImmutableBitmap64(cpointer *C.roaring64_bitmap_t, buffer *byte, release func() error) *ImmutableBitmap64 {
+	if cpointer == nil {
+		return nil
+	}
+	answer := &ImmutableBitmap64{Bitmap64{cpointer}, buffer, release}
+	track(unsafe.Pointer(cpointer), kindImmutableBitmap64)
+	runtime.SetFinalizer(answer, finalizeImmutableBitmap64)
+	return answer
+}
+
+func finalizeImmutableBitmap64(ib *ImmutableBitmap64) {
+	untrack(unsafe.Pointer(ib.rb.cpointer), true)
+	ib.Close()
+}
+
+func (ib *ImmutableBitmap64) cbitmap64(op string) *C.roaring64_bitmap_t {
+	return ib.rb.cbitmap64(op)
+}
+
+// Close frees the bitmap and releases the memory backing it, it implements io.Closer
+func (ib *ImmutableBitmap64) Close() error {
+	runtime.SetFinalizer(ib, nil)
+	if ib.rb.cpointer == nil {
+		return nil
+	}
+	untrack(unsafe.Pointer(ib.rb.cpointer), false)
+	C.roaring64_bitmap_free(ib.rb.cpointer)
+	ib.rb.cpointer = nil
+	ib.buffer = nil
+	if ib.release != nil {
+		return ib.release()
+	}
+	return nil
+}
+
+// Free is the same as Close
+func (ib *ImmutableBitmap64) Free() {
+	ib.Close()
+}
+
+// Clone creates a mutable copy of the bitmap
+// This function may panic if the allocation failed.
+func (ib *ImmutableBitmap64) Clone() *Bitmap64 {
+	return ib.rb.Clone()
+}
+
+// Contains returns true if the integer is contained in the bitmap
+func (ib *ImmutableBitmap64) Contains(x uint64) bool {
+	return ib.rb.Contains(x)
+}
+
+// ContainsRange returns true if the integers in the range [x, y) are contained in the bitmap
+func (ib *ImmutableBitmap64) ContainsRange(x, y uint64) bool {
+	return ib.rb.ContainsRange(x, y)
+}
+
+// Cardinality returns the number of integers contained in the bitmap
+func (ib *ImmutableBitmap64) Cardinality() uint64 {
+	return ib.rb.Cardinality()
+}
+
+// GetCardinality returns the number of integers contained in the bitmap
+func (ib *ImmutableBitmap64) GetCardinality() uint64 {
+	return ib.rb.Cardinality()
+}
+
+// IsEmpty returns true if the bitmap is empty
+func (ib *ImmutableBitmap64) IsEmpty() bool {
+	return ib.rb.IsEmpty()
+}
+
+// Maximum returns the largest of the integers contained in the bitmap assuming that it is not empty
+func (ib *ImmutableBitmap64) Maximum() uint64 {
+	return ib.rb.Maximum()
+}
+
+// Minimum returns the smallest of the integers contained in the bitmap assuming that it is not empty
+func (ib *ImmutableBitmap64) Minimum() uint64 {
+	return ib.rb.Minimum()
+}
+
+// MaximumOK returns the largest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (ib *ImmutableBitmap64) MaximumOK() (x uint64, ok bool) {
+	return ib.rb.MaximumOK()
+}
+
+// MinimumOK returns the smallest of the integers contained in the bitmap, ok is false if the bitmap is empty
+func (ib *ImmutableBitmap64) MinimumOK() (x uint64, ok bool) {
+	return ib.rb.MinimumOK()
+}
+
+// Rank returns the number of values smaller or equal to x
+func (ib *ImmutableBitmap64) Rank(x uint64) uint64 {
+	return ib.rb.Rank(x)
+}
+
+// Select returns the element having the designated rank, if it exists
+func (ib *ImmutableBitmap64) Select(rank uint64) (uint64, error) {
+	return ib.rb.Select(rank)
+}
+
+// Equals returns true if the two bitmaps contain the same integers
+func (ib *ImmutableBitmap64) Equals(o interface{}) bool {
+	return ib.rb.Equals(o)
+}
+
+// Intersect checks whether the two bitmaps intersect
+func (ib *ImmutableBitmap64) Intersect(x2 ReadOnlyBitmap64) bool {
+	return ib.rb.Intersect(x2)
+}
+
+// JaccardIndex computes the Jaccard index between two bitmaps
+func (ib *ImmutableBitmap64) JaccardIndex(x2 ReadOnlyBitmap64) float64 {
+	return ib.rb.JaccardIndex(x2)
+}
+
+// AndCardinality computes the size of the intersection between two bitmaps
+func (ib *ImmutableBitmap64) AndCardinality(x2 ReadOnlyBitmap64) uint64 {
+	return ib.rb.AndCardinality(x2)
+}
+
+// XorCardinality computes the size of the symmetric difference between two bitmaps
+func (ib *ImmutableBitmap64) XorCardinality(x2 ReadOnlyBitmap64) uint64 {
+	return ib.rb.XorCardinality(x2)
+}
+
+// OrCardinality computes the size of the union between two bitmaps
+func (ib *ImmutableBitmap64) OrCardinality(x2 ReadOnlyBitmap64) uint64 {
+	return ib.rb.OrCardinality(x2)
+}
+
+// AndNotCardinality computes the size of the difference between two bitmaps
+func (ib *ImmutableBitmap64) AndNotCardinality(x2 ReadOnlyBitmap64) uint64 {
+	return ib.rb.AndNotCardinality(x2)
+}
+
+// ToArray creates a new slice containing all of the integers stored in the bitmap in sorted order
+func (ib *ImmutableBitmap64) ToArray() []uint64 {
+	return ib.rb.ToArray()
+}
+
+// String creates a string representation of the bitmap
+func (ib *ImmutableBitmap64) String() string {
+	return ib.rb.String()
+}
+
+// SerializedSizeInBytes computes the serialized size in bytes of the bitmap.
+func (ib *ImmutableBitmap64) SerializedSizeInBytes() int {
+	return ib.rb.SerializedSizeInBytes()
+}
+
+// Write writes a serialized version of this bitmap to stream (you should have enough space)
+func (ib *ImmutableBitmap64) Write(b []byte) error {
+	return ib.rb.Write(b)
+}
+
+// ToBitmaps splits the bitmap into 32-bit bitmaps, keyed by the upper 32 bits of their integers.
+func (ib *ImmutableBitmap64) ToBitmaps() map[uint32]*Bitmap {
+	return ib.rb.ToBitmaps()
+}
@@ -0,0 +1,108 @@
+package gocroaring
+
+import (
+	"testing"
+)
+
+func TestImmutableBitmap(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		buf := AlignedBuffer(rb.FrozenSizeInBytes())
+		if err := rb.WriteFrozen(buf); err != nil {
+			t.Fatal(err)
+		}
+		view, err := ReadFrozenView(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !view.Equals(rb) || !rb.Equals(view) {
+			t.Error("bad frozen view")
+		}
+		if view.Cardinality() != rb.Cardinality() || view.IsEmpty() != rb.IsEmpty() {
+			t.Error("bad cardinality")
+		}
+
+		// a view can be the operand of any operation
+		other := New(1, 2, 3, 1<<20)
+		if And(other, view).Cardinality() != other.AndCardinality(view) {
+			t.Error("bad And")
+		}
+		if Or(view, other).Cardinality() != view.OrCardinality(other) {
+			t.Error("bad Or")
+		}
+		union := other.Clone()
+		union.Or(view)
+		if !union.Equals(Or(other, rb)) {
+			t.Error("bad in-place Or")
+		}
+
+		// Clone gives a mutable copy that does not affect the view
+		mutable := view.Clone()
+		mutable.Add(12345678)
+		mutable.Remove(1)
+		if !view.Equals(rb) {
+			t.Error("the view changed")
+		}
+		if err := view.Close(); err != nil {
+			t.Error(err)
+		}
+		view.Free() // freeing twice is harmless
+	}
+}
+
+func TestImmutableBitmapIterators(t *testing.T) {
+	rb := New(1, 5, 100, 1<<20, 1<<30)
+	rb.AddRange(2000, 3000)
+	buf := make([]byte, rb.SerializedSizeInBytes())
+	rb.Write(buf)
+	view, err := ReadPortableView(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Free()
+	var fromIterator []uint32
+	for it := view.Iterator(); it.HasNext(); {
+		fromIterator = append(fromIterator, it.Next())
+	}
+	expected := rb.ToArray()
+	if len(fromIterator) != len(expected) {
+		t.Fatalf("expected %d values, got %d", len(expected), len(fromIterator))
+	}
+	for i := range expected {
+		if fromIterator[i] != expected[i] {
+			t.Fatalf("bad value at %d", i)
+		}
+	}
+	top := view.TopK(2)
+	if len(top) != 2 || top[0] != 1<<30 || top[1] != 1<<20 {
+		t.Errorf("bad TopK %v", top)
+	}
+	if view.IntervalCount() != rb.IntervalCount() {
+		t.Error("bad IntervalCount")
+	}
+}
+
+func TestImmutableBitmap64(t *testing.T) {
+	rb := New64(1, 2, 1<<40, 1<<63)
+	rb.AddRange(1<<33, 1<<33+100000)
+	buf := AlignedBuffer(rb.FrozenSizeInBytes())
+	if err := rb.WriteFrozen(buf); err != nil {
+		t.Fatal(err)
+	}
+	view, err := ReadFrozenView64(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer view.Free()
+	if !view.Equals(rb) || !rb.Equals(view) {
+		t.Error("bad frozen view")
+	}
+	other := New64(2, 3, 1<<40)
+	if And64(view, other).Cardinality() != 2 || other.AndCardinality(view) != 2 {
+		t.Error("bad And64")
+	}
+	mutable := view.Clone()
+	mutable.Add(7)
+	if view.Contains(7) || !mutable.Contains(7) {
+		t.Error("bad Clone")
+	}
+}
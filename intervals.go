@@ -0,0 +1,139 @@
+package gocroaring
+
+/*
+#cgo CFLAGS: -O3  -std=c11
+#include "roaring.h"
+
+typedef struct {
+	uint64_t *out; // start and end of each interval, NULL when only counting
+	size_t capacity;
+	size_t count;
+	uint64_t start;
+	uint64_t end;
+	bool pending;
+} gocroaring_intervals_t;
+
+static void gocroaring_intervals_flush(gocroaring_intervals_t *s) {
+	if (s->out != NULL && s->count < s->capacity) {
+		s->out[2 * s->count] = s->start;
+		s->out[2 * s->count + 1] = s->end;
+	}
+	s->count++;
+}
+
+// gocroaring_intervals_emit records [start, end), merging it with the previous interval when they touch.
+static void gocroaring_intervals_emit(gocroaring_intervals_t *s, uint64_t start, uint64_t end) {
+	if (s->pending && s->end == start) {
+		s->end = end;
+		return;
+	}
+	if (s->pending) {
+		gocroaring_intervals_flush(s);
+	}
+	s->start = start;
+	s->end = end;
+	s->pending = true;
+}
+
+// gocroaring_intervals walks the containers of r and writes up to capacity maximal
+// intervals [start, end) to out. It returns the total number of intervals.
+static size_t gocroaring_intervals(const roaring_bitmap_t *r, uint64_t *out, size_t capacity) {
+	gocroaring_intervals_t s = {out, capacity, 0, 0, 0, false};
+	const roaring_array_t *ra = &r->high_low_container;
+	for (int32_t i = 0; i < ra->size; i++) {
+		uint8_t typecode = ra->typecodes[i];
+		const container_t *c = container_unwrap_shared(ra->containers[i], &typecode);
+		uint64_t high = (uint64_t)ra->keys[i] << 16;
+		switch (typecode) {
+		case RUN_CONTAINER_TYPE: {
+			const run_container_t *run = const_CAST_run(c);
+			for (int32_t j = 0; j < run->n_runs; j++) {
+				uint64_t start = high + run->runs[j].value;
+				gocroaring_intervals_emit(&s, start, start + run->runs[j].length + 1);
+			}
+			break;
+		}
+		case ARRAY_CONTAINER_TYPE: {
+			const array_container_t *array = const_CAST_array(c);
+			for (int32_t j = 0; j < array->cardinality; j++) {
+				uint64_t start = high + array->array[j];
+				gocroaring_intervals_emit(&s, start, start + 1);
+			}
+			break;
+		}
+		case BITSET_CONTAINER_TYPE: {
+			const bitset_container_t *bitset = const_CAST_bitset(c);
+			for (int32_t w = 0; w < BITSET_CONTAINER_SIZE_IN_WORDS; w++) {
+				uint64_t word = bitset->words[w];
+				while (word != 0) {
+					int t = roaring_trailing_zeroes(word);
+					uint64_t shifted = ~(word >> t);
+					int ones = shifted == 0 ? 64 - t : roaring_trailing_zeroes(shifted);
+					uint64_t start = high + 64 * (uint64_t)w + t;
+					gocroaring_intervals_emit(&s, start, start + ones);
+					word = (t + ones == 64) ? 0 : word & ~(((UINT64_C(1) << ones) - 1) << t);
+				}
+			}
+			break;
+		}
+		}
+	}
+	if (s.pending) {
+		gocroaring_intervals_flush(&s);
+	}
+	return s.count;
+}
+
+// gocroaring_add_intervals adds the n intervals [start, end) stored in pairs to r.
+static void gocroaring_add_intervals(roaring_bitmap_t *r, const uint64_t *pairs, size_t n) {
+	for (size_t i = 0; i < n; i++) {
+		roaring_bitmap_add_range(r, pairs[2 * i], pairs[2 * i + 1]);
+	}
+}
+
+*/
+import "C"
+import (
+	"runtime"
+	"unsafe"
+)
+
+// Interval is the range of integers [Start, End): Start is included and End is excluded, so that
+// End can be 1<<32 for a run ending with the largest uint32. Intervals, FromIntervals and Ranges
+// all describe runs of integers this way.
+type Interval struct {
+	Start uint64
+	End   uint64
+}
+
+// IntervalCount returns the number of maximal runs of consecutive integers contained in the bitmap
+func (rb *Bitmap) IntervalCount() int {
+	rb.check("IntervalCount")
+	answer := int(C.gocroaring_intervals(rb.cpointer, nil, 0))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// Intervals returns the maximal runs of consecutive integers contained in the bitmap, in sorted order.
+// Run containers are read directly, so this is much faster than iterating when the bitmap was run-optimized.
+func (rb *Bitmap) Intervals() []Interval {
+	rb.check("Intervals")
+	answer := make([]Interval, rb.IntervalCount())
+	if len(answer) > 0 {
+		C.gocroaring_intervals(rb.cpointer, (*C.uint64_t)(unsafe.Pointer(&answer[0])), C.size_t(len(answer)))
+	}
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// FromIntervals creates a new Bitmap containing all the integers in the given intervals
+// This function may panic if the allocation failed.
+func FromIntervals(intervals []Interval) *Bitmap {
+	answer := New()
+	if len(intervals) > 0 {
+		C.gocroaring_add_intervals(answer.cpointer, (*C.uint64_t)(unsafe.Pointer(&intervals[0])), C.size_t(len(intervals)))
+		runtime.KeepAlive(intervals)
+	}
+	runtime.KeepAlive(answer)
+	return answer
+}
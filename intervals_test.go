@@ -0,0 +1,62 @@
+package gocroaring
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// naiveIntervals computes the intervals of rb one integer at a time
+func naiveIntervals(rb *Bitmap) []Interval {
+	answer := []Interval{}
+	for _, v := range rb.ToArray() {
+		if n := len(answer); n > 0 && answer[n-1].End == uint64(v) {
+			answer[n-1].End++
+		} else {
+			answer = append(answer, Interval{uint64(v), uint64(v) + 1})
+		}
+	}
+	return answer
+}
+
+func TestIntervals(t *testing.T) {
+	rb := New(1, 2, 3, 7, 0xFFFFFFFF)
+	rb.AddRange(65530, 65600)        // crosses a container boundary
+	rb.AddRange(1<<20, 1<<20+200000) // several full containers
+	for i := 0; i < 10000; i++ {
+		rb.Add(uint32(1<<24 + rand.Intn(65536)))
+	}
+	if rb.StatsStruct().BitmapContainers == 0 {
+		t.Fatal("expected a bitset container")
+	}
+	expected := naiveIntervals(rb)
+	for _, optimize := range []bool{false, true} {
+		if optimize {
+			rb.RunOptimize()
+		}
+		got := rb.Intervals()
+		if !reflect.DeepEqual(expected, got) {
+			t.Errorf("run optimized %v: expected %d intervals, got %d", optimize, len(expected), len(got))
+		}
+		if rb.IntervalCount() != len(expected) {
+			t.Errorf("run optimized %v: expected %d intervals, got %d", optimize, len(expected), rb.IntervalCount())
+		}
+	}
+	if got := rb.Intervals()[:3]; !reflect.DeepEqual(got, []Interval{{1, 4}, {7, 8}, {65530, 65600}}) {
+		t.Errorf("bad intervals %v", got)
+	}
+	if got := rb.Intervals()[len(expected)-1]; got != (Interval{0xFFFFFFFF, 1 << 32}) {
+		t.Errorf("bad last interval %v", got)
+	}
+	if !FromIntervals(expected).Equals(rb) {
+		t.Error("FromIntervals should rebuild the bitmap")
+	}
+	if len(New().Intervals()) != 0 || New().IntervalCount() != 0 || !FromIntervals(nil).IsEmpty() {
+		t.Error("expected no intervals")
+	}
+
+	full := FromIntervals([]Interval{{0, 1 << 32}})
+	if full.Cardinality() != 1<<32 || !reflect.DeepEqual(full.Intervals(), []Interval{{0, 1 << 32}}) {
+		t.Error("bad full bitmap")
+	}
+}
@@ -0,0 +1,91 @@
+//go:build go1.23
+
+package gocroaring
+
+import "iter"
+
+// iterBatchSize is the number of integers read from C at once by the iter.Seq functions
+const iterBatchSize = 256
+
+// Values returns an iterator over the integers contained in the bitmap, in sorted order
+func (rb *Bitmap) Values() iter.Seq[uint32] {
+	rb.check("Values")
+	return func(yield func(uint32) bool) {
+		rb.check("Values")
+		it := newIntIterator(rb)
+		defer it.free()
+		var buf [iterBatchSize]uint32
+		for n := it.NextMany(buf[:]); n > 0; n = it.NextMany(buf[:]) {
+			for _, v := range buf[:n] {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Backward returns an iterator over the integers contained in the bitmap, in decreasing order
+func (rb *Bitmap) Backward() iter.Seq[uint32] {
+	rb.check("Backward")
+	return func(yield func(uint32) bool) {
+		rb.check("Backward")
+		it := newReverseIntIterator(rb)
+		defer it.free()
+		var buf [iterBatchSize]uint32
+		for n := it.NextMany(buf[:]); n > 0; n = it.NextMany(buf[:]) {
+			for _, v := range buf[:n] {
+				if !yield(v) {
+					return
+				}
+			}
+		}
+	}
+}
+
+// Ranges returns an iterator over the maximal runs of consecutive integers contained in the bitmap,
+// in sorted order. It yields the start and end of each run, as the half-open Interval values
+// returned by Intervals, without building a slice.
+func (rb *Bitmap) Ranges() iter.Seq2[uint64, uint64] {
+	rb.check("Ranges")
+	return func(yield func(uint64, uint64) bool) {
+		rb.check("Ranges")
+		it := newIntIterator(rb)
+		defer it.free()
+		var buf [iterBatchSize]uint32
+		var start, end uint64
+		started := false
+		for n := it.NextMany(buf[:]); n > 0; n = it.NextMany(buf[:]) {
+			for _, v := range buf[:n] {
+				if started && uint64(v) == end {
+					end++
+					continue
+				}
+				if started && !yield(start, end) {
+					return
+				}
+				start, end, started = uint64(v), uint64(v)+1, true
+			}
+		}
+		if started {
+			yield(start, end)
+		}
+	}
+}
+
+// Values returns an iterator over the integers contained in the bitmap, in sorted order
+func (ib *readOnlyBitmap) Values() iter.Seq[uint32] {
+	return ib.rb.Values()
+}
+
+// Backward returns an iterator over the integers contained in the bitmap, in decreasing order
+func (ib *readOnlyBitmap) Backward() iter.Seq[uint32] {
+	return ib.rb.Backward()
+}
+
+// Ranges returns an iterator over the maximal runs of consecutive integers contained in the bitmap,
+// in sorted order. It yields the start and end of each run, as the half-open Interval values
+// returned by Intervals, without building a slice.
+func (ib *readOnlyBitmap) Ranges() iter.Seq2[uint64, uint64] {
+	return ib.rb.Ranges()
+}
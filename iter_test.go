@@ -0,0 +1,101 @@
+//go:build go1.23
+
+package gocroaring
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestValues(t *testing.T) {
+	rb := New()
+	for i := 0; i < 10000; i++ {
+		rb.Add(uint32(rand.Intn(10000000)))
+	}
+	rb.Add(0xFFFFFFFF)
+	expected := rb.ToArray()
+	var got []uint32
+	for v := range rb.Values() {
+		got = append(got, v)
+	}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %d values, got %d", len(expected), len(got))
+	}
+
+	got = got[:0]
+	for v := range rb.Backward() {
+		got = append(got, v)
+	}
+	for i := range got {
+		if got[i] != expected[len(expected)-1-i] {
+			t.Fatalf("bad value at position %d", i)
+		}
+	}
+
+	count := 0
+	for range rb.Values() {
+		count++
+		if count == 300 {
+			break
+		}
+	}
+	if count != 300 {
+		t.Error("expected to stop early")
+	}
+	for range New().Values() {
+		t.Error("expected nothing to iterate over")
+	}
+}
+
+func TestRanges(t *testing.T) {
+	rb := New(0, 1, 2, 5, 70000, 0xFFFFFFFE, 0xFFFFFFFF)
+	rb.AddRange(65530, 65600)
+	rb.AddRange(100, 1000)
+	var got []Interval
+	for start, end := range rb.Ranges() {
+		got = append(got, Interval{start, end})
+	}
+	expected := []Interval{{0, 3}, {5, 6}, {100, 1000}, {65530, 65600}, {70000, 70001}, {0xFFFFFFFE, 1 << 32}}
+	if !reflect.DeepEqual(expected, got) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if !reflect.DeepEqual(rb.Intervals(), got) {
+		t.Errorf("Ranges and Intervals disagree: %v and %v", got, rb.Intervals())
+	}
+	for start, end := range rb.Ranges() {
+		if start != 0 || end != 3 {
+			t.Error("bad first run")
+		}
+		break
+	}
+	for range New().Ranges() {
+		t.Error("expected nothing to iterate over")
+	}
+}
+
+func TestValuesAfterFree(t *testing.T) {
+	rb := New(1, 2, 3)
+	values, backward, ranges := rb.Values(), rb.Backward(), rb.Ranges()
+	rb.Free()
+	expectPanic(t, "Values", func() {
+		for range values {
+		}
+	})
+	expectPanic(t, "Backward", func() {
+		for range backward {
+		}
+	})
+	expectPanic(t, "Ranges", func() {
+		for range ranges {
+		}
+	})
+
+	sb := NewSharedBitmap(New(1, 2, 3))
+	values = sb.Values()
+	sb.Release()
+	expectPanic(t, "Values", func() {
+		for range values {
+		}
+	})
+}
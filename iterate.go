@@ -0,0 +1,30 @@
+package gocroaring
+
+/*
+#include "roaring.h"
+
+extern bool gocroaringIterateCallback(uint32_t value, void *param);
+*/
+import "C"
+import (
+	"runtime"
+	"runtime/cgo"
+	"unsafe"
+)
+
+//export gocroaringIterateCallback
+func gocroaringIterateCallback(value C.uint32_t, param unsafe.Pointer) C.bool {
+	f := (*(*cgo.Handle)(param)).Value().(func(uint32) bool)
+	return C.bool(f(uint32(value)))
+}
+
+// Iterate calls f on the integers contained in the bitmap, in sorted order, until f returns false.
+// The walk happens in C, so no iterator needs to be allocated, but every integer costs
+// a call from C into Go: prefer ManyIterator when scanning large bitmaps.
+func (rb *Bitmap) Iterate(f func(x uint32) bool) {
+	rb.check("Iterate")
+	h := cgo.NewHandle(f)
+	defer h.Delete()
+	C.roaring_iterate(rb.cpointer, C.roaring_iterator(C.gocroaringIterateCallback), unsafe.Pointer(&h))
+	runtime.KeepAlive(rb)
+}
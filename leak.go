@@ -0,0 +1,25 @@
+package gocroaring
+
+// objectKind tells what a C object tracked by the leak detector is
+type objectKind int
+
+const (
+	kindBitmap objectKind = iota
+	kindBitmap64
+	kindImmutableBitmap
+	kindImmutableBitmap64
+	kindIterator
+)
+
+var objectKindNames = [...]string{"Bitmap", "Bitmap64", "ImmutableBitmap", "ImmutableBitmap64", "Iterator"}
+
+func (k objectKind) String() string {
+	return objectKindNames[k]
+}
+
+// LiveBitmap describes a bitmap, view or iterator that has not been freed yet, see LiveBitmaps.
+type LiveBitmap struct {
+	Kind  string // "Bitmap", "Bitmap64", "ImmutableBitmap", "ImmutableBitmap64" or "Iterator"
+	Size  uint64 // bytes held by the containers (or the iterator)
+	Stack string // where it was created
+}
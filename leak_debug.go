@@ -0,0 +1,128 @@
+//go:build gocroaring_debug
+
+package gocroaring
+
+/*
+#include "roaring.h"
+
+static uint64_t gocroaring_tracked_size(void *p, int kind) {
+	switch (kind) {
+	case 0:
+	case 2: {
+		roaring_statistics_t stat;
+		roaring_bitmap_statistics((const roaring_bitmap_t *)p, &stat);
+		return stat.n_bytes_array_containers + stat.n_bytes_run_containers + stat.n_bytes_bitset_containers;
+	}
+	case 1:
+	case 3: {
+		roaring64_statistics_t stat;
+		roaring64_bitmap_statistics((const roaring64_bitmap_t *)p, &stat);
+		return stat.n_bytes_array_containers + stat.n_bytes_run_containers + stat.n_bytes_bitset_containers;
+	}
+	}
+	return sizeof(roaring_uint32_iterator_t);
+}
+*/
+import "C"
+import (
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+type trackedObject struct {
+	kind  objectKind
+	stack []uintptr
+}
+
+// tracked maps the C objects held by Go objects to where they were created
+var tracked struct {
+	sync.Mutex
+	objects map[unsafe.Pointer]trackedObject
+	// warnings about objects reclaimed by a finalizer
+	minSize uint64
+	warn    func(LiveBitmap)
+}
+
+// track records that a Go object now holds the C object p
+func track(p unsafe.Pointer, kind objectKind) {
+	stack := make([]uintptr, 32)
+	stack = stack[:runtime.Callers(3, stack)]
+	tracked.Lock()
+	if tracked.objects == nil {
+		tracked.objects = make(map[unsafe.Pointer]trackedObject)
+	}
+	tracked.objects[p] = trackedObject{kind, stack}
+	tracked.Unlock()
+}
+
+// untrack records that the C object p is about to be freed, by a finalizer if finalized is true
+func untrack(p unsafe.Pointer, finalized bool) {
+	tracked.Lock()
+	object, ok := tracked.objects[p]
+	delete(tracked.objects, p)
+	warn, minSize := tracked.warn, tracked.minSize
+	tracked.Unlock()
+	if !ok || !finalized || warn == nil {
+		return
+	}
+	if live := describe(p, object); live.Size >= minSize {
+		warn(live)
+	}
+}
+
+func describe(p unsafe.Pointer, object trackedObject) LiveBitmap {
+	return LiveBitmap{
+		Kind:  object.kind.String(),
+		Size:  uint64(C.gocroaring_tracked_size(p, C.int(object.kind))),
+		Stack: formatStack(object.stack),
+	}
+}
+
+// callerStack returns the stack trace of its caller
+func callerStack() string {
+	pcs := make([]uintptr, 32)
+	return formatStack(pcs[:runtime.Callers(2, pcs)])
+}
+
+func formatStack(pcs []uintptr) string {
+	var stack strings.Builder
+	frames := runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		stack.WriteString(frame.Function)
+		stack.WriteString("\n\t")
+		stack.WriteString(frame.File)
+		stack.WriteString(":")
+		stack.WriteString(strconv.Itoa(frame.Line))
+		stack.WriteString("\n")
+		if !more {
+			break
+		}
+	}
+	return stack.String()
+}
+
+// LiveBitmaps returns the bitmaps, views and iterators that have not been freed yet, with the
+// stack trace of their creation. It is only available with the gocroaring_debug build tag,
+// otherwise it returns nil.
+func LiveBitmaps() []LiveBitmap {
+	tracked.Lock()
+	defer tracked.Unlock()
+	answer := make([]LiveBitmap, 0, len(tracked.objects))
+	for p, object := range tracked.objects {
+		answer = append(answer, describe(p, object))
+	}
+	return answer
+}
+
+// SetLeakWarning makes the finalizers call f when they reclaim a bitmap, view or iterator holding
+// at least minSize bytes, which should have been freed explicitly. Pass a nil f to remove the warning.
+// It is only available with the gocroaring_debug build tag, otherwise it does nothing.
+func SetLeakWarning(minSize uint64, f func(LiveBitmap)) {
+	tracked.Lock()
+	tracked.minSize, tracked.warn = minSize, f
+	tracked.Unlock()
+}
@@ -0,0 +1,138 @@
+//go:build gocroaring_debug
+
+package gocroaring
+
+import (
+	"strings"
+	"testing"
+)
+
+// liveCreatedBy returns the live objects created by the named function
+func liveCreatedBy(function string) []LiveBitmap {
+	var answer []LiveBitmap
+	for _, live := range LiveBitmaps() {
+		if strings.Contains(live.Stack, function) {
+			answer = append(answer, live)
+		}
+	}
+	return answer
+}
+
+func TestLiveBitmaps(t *testing.T) {
+	rb := New()
+	rb.AddRange(0, 100000)
+	rb.Add(1 << 30)
+	it := rb.Iterator()
+	buf := make([]byte, rb.SerializedSizeInBytes())
+	rb.Write(buf)
+	view, err := ReadPortableView(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb64 := New64(1, 1<<40)
+
+	live := liveCreatedBy("TestLiveBitmaps")
+	kinds := map[string]LiveBitmap{}
+	for _, l := range live {
+		kinds[l.Kind] = l
+	}
+	for _, kind := range []string{"Bitmap", "Iterator", "ImmutableBitmap", "Bitmap64"} {
+		if _, ok := kinds[kind]; !ok {
+			t.Errorf("%s is missing from %v", kind, live)
+		}
+	}
+	if kinds["Bitmap"].Size == 0 || kinds["ImmutableBitmap"].Size == 0 {
+		t.Errorf("bad sizes %v", live)
+	}
+
+	rb.Free()
+	view.Free()
+	rb64.Free()
+	for _, l := range liveCreatedBy("TestLiveBitmaps") {
+		if l.Kind != "Iterator" {
+			t.Errorf("%s was freed", l.Kind)
+		}
+	}
+	_ = it
+}
+
+func TestReplaceTracksBitmap(t *testing.T) {
+	other := New(4)
+	data := other.AppendTo(nil)
+	other.Free()
+
+	rb := New(1)
+	for _, replace := range []func() error{
+		func() error { return rb.UnmarshalJSON([]byte("[1,2,3]")) },
+		func() error { return rb.UnmarshalBinary(data) },
+		func() error { return rb.UnmarshalJSON([]byte("[]")) },
+	} {
+		if err := replace(); err != nil {
+			t.Fatal(err)
+		}
+		if live := liveCreatedBy("TestReplaceTracksBitmap"); len(live) != 1 {
+			t.Errorf("expected the new bitmap to be tracked, got %v", live)
+		}
+	}
+	rb.Free()
+	if live := liveCreatedBy("TestReplaceTracksBitmap"); len(live) != 0 {
+		t.Errorf("expected no live bitmap, got %v", live)
+	}
+}
+
+func TestTopKFreesIterator(t *testing.T) {
+	rb := New(1, 2, 3)
+	rb.TopK(2)
+	rb.Free()
+	if live := liveCreatedBy("TestTopKFreesIterator"); len(live) != 0 {
+		t.Errorf("expected no live object, got %v", live)
+	}
+}
+
+func TestLeakWarning(t *testing.T) {
+	warnings := make(chan LiveBitmap, 10)
+	SetLeakWarning(1000, func(live LiveBitmap) {
+		if strings.Contains(live.Stack, "leakLargeBitmap") {
+			warnings <- live
+		}
+	})
+	defer SetLeakWarning(0, nil)
+	leakLargeBitmap()
+	freeLargeBitmap()
+	collectBitmaps()
+	select {
+	case live := <-warnings:
+		if live.Kind != "Bitmap" || live.Size < 1000 {
+			t.Errorf("bad warning %+v", live)
+		}
+	default:
+		t.Fatal("no warning")
+	}
+	if len(warnings) != 0 {
+		t.Error("too many warnings")
+	}
+}
+
+func leakLargeBitmap() {
+	bitsetBitmap(2)
+}
+
+func freeLargeBitmap() {
+	bitsetBitmap(2).Free()
+}
+
+func releaseShared(sb *SharedBitmap) {
+	sb.Release()
+}
+
+func TestSharedBitmapOverRelease(t *testing.T) {
+	sb := NewSharedBitmap(New(1, 2, 3))
+	releaseShared(sb)
+	defer func() {
+		msg, _ := recover().(string)
+		if !strings.Contains(msg, "releaseShared") {
+			t.Errorf("expected the stack of the last release, got %q", msg)
+		}
+	}()
+	sb.Release()
+}
@@ -0,0 +1,28 @@
+//go:build !gocroaring_debug
+
+package gocroaring
+
+import "unsafe"
+
+// track records that a Go object now holds the C object p (only with the gocroaring_debug build tag)
+func track(p unsafe.Pointer, kind objectKind) {}
+
+// untrack records that the C object p is about to be freed (only with the gocroaring_debug build tag)
+func untrack(p unsafe.Pointer, finalized bool) {}
+
+// callerStack returns the stack trace of its caller (only with the gocroaring_debug build tag)
+func callerStack() string {
+	return ""
+}
+
+// LiveBitmaps returns the bitmaps, views and iterators that have not been freed yet, with the
+// stack trace of their creation. It is only available with the gocroaring_debug build tag,
+// otherwise it returns nil.
+func LiveBitmaps() []LiveBitmap {
+	return nil
+}
+
+// SetLeakWarning makes the finalizers call f when they reclaim a bitmap, view or iterator holding
+// at least minSize bytes, which should have been freed explicitly. Pass a nil f to remove the warning.
+// It is only available with the gocroaring_debug build tag, otherwise it does nothing.
+func SetLeakWarning(minSize uint64, f func(LiveBitmap)) {}
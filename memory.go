@@ -0,0 +1,693 @@
+package gocroaring
+
+/*
+#include <setjmp.h>
+#include <stdatomic.h>
+#include <stddef.h>
+#include <stdbool.h>
+#include <stdint.h>
+#include <stdlib.h>
+#include "roaring.h"
+
+extern void gocroaringMemoryCallback(void);
+
+// gocroaring_budget_t accounts for the bytes of the blocks charged to it.
+typedef struct gocroaring_budget_s {
+	atomic_size_t used;
+	atomic_size_t limit; // 0 means unlimited
+	atomic_size_t refs;  // one for the Go Budget, plus one per block
+} gocroaring_budget_t;
+
+typedef struct gocroaring_try_s gocroaring_try_t;
+
+// Every block handed to CRoaring is preceded by a header, so that the free functions
+// know how many bytes are released and what they were charged to.
+typedef struct gocroaring_block_s {
+	_Alignas(max_align_t) size_t size;
+	uint32_t offset; // from the start of the underlying allocation to the end of the header
+	bool counted;    // charged to the process budget, which only happens once the accounting is on
+	bool extended;   // allocated during a Try call, the header is preceded by a gocroaring_extension_t
+} gocroaring_block_t;
+
+// gocroaring_extension_t tells which Try call allocated a block. Other blocks go without it,
+// to keep the memory overhead low.
+typedef struct gocroaring_extension_s {
+	// while owner is set, the block belongs to the list of blocks allocated by the pending Try call
+	_Alignas(max_align_t) struct gocroaring_extension_s *prev;
+	struct gocroaring_extension_s *next;
+	gocroaring_try_t *owner;
+	gocroaring_budget_t *budget; // the Budget of the Try call, NULL for the process budget
+} gocroaring_extension_t;
+
+static gocroaring_extension_t *gocroaring_extension(gocroaring_block_t *b) {
+	return (gocroaring_extension_t *)b - 1;
+}
+
+static gocroaring_block_t *gocroaring_extended_block(gocroaring_extension_t *e) {
+	return (gocroaring_block_t *)(e + 1);
+}
+
+// gocroaring_try_t is a call to CRoaring that gives up, instead of allocating memory,
+// once a budget is exceeded
+struct gocroaring_try_s {
+	jmp_buf env;
+	gocroaring_budget_t *budget;
+	gocroaring_extension_t *blocks;
+	gocroaring_try_t *previous;
+};
+
+// Once gocroaring_accounting is set, the process budget is charged for every block. It is
+// off by default because its counters are shared by all threads.
+static atomic_bool gocroaring_accounting;
+static gocroaring_budget_t gocroaring_process_budget = {0, 0, 1};
+static atomic_size_t gocroaring_peak_bytes;
+static atomic_uint_fast64_t gocroaring_live_allocations;
+static atomic_uint_fast64_t gocroaring_total_allocations;
+static _Thread_local gocroaring_try_t *gocroaring_current_try;
+
+// When gocroaring_notify_step is not zero, gocroaringMemoryCallback is called whenever the
+// live bytes moved by at least that much since the last call.
+static atomic_size_t gocroaring_notify_step;
+static atomic_size_t gocroaring_notified_bytes;
+static _Thread_local bool gocroaring_in_callback;
+
+static void gocroaring_maybe_notify(size_t live) {
+	size_t step = atomic_load(&gocroaring_notify_step);
+	if (step == 0 || gocroaring_in_callback) {
+		return;
+	}
+	size_t notified = atomic_load(&gocroaring_notified_bytes);
+	size_t delta = live > notified ? live - notified : notified - live;
+	if (delta < step || !atomic_compare_exchange_strong(&gocroaring_notified_bytes, &notified, live)) {
+		return;
+	}
+	gocroaring_in_callback = true;
+	gocroaringMemoryCallback();
+	gocroaring_in_callback = false;
+}
+
+static bool gocroaring_exceeds(gocroaring_budget_t *budget, size_t used) {
+	size_t limit = atomic_load(&budget->limit);
+	return limit != 0 && used > limit;
+}
+
+static void gocroaring_uncharge(gocroaring_budget_t *budget, bool counted, size_t size) {
+	if (budget != NULL) {
+		atomic_fetch_sub(&budget->used, size);
+	}
+	if (counted) {
+		size_t live = atomic_fetch_sub(&gocroaring_process_budget.used, size) - size;
+		gocroaring_maybe_notify(live);
+	}
+}
+
+// gocroaring_charge accounts for size more bytes. During a Try call, it jumps out of
+// the call instead if that exceeds the process budget or the budget of the call.
+static void gocroaring_charge(gocroaring_budget_t *budget, bool counted, size_t size) {
+	size_t used = 0, live = 0;
+	if (budget != NULL) {
+		used = atomic_fetch_add(&budget->used, size) + size;
+	}
+	if (counted) {
+		live = atomic_fetch_add(&gocroaring_process_budget.used, size) + size;
+	}
+	gocroaring_try_t *try = gocroaring_current_try;
+	if (try != NULL && ((counted && gocroaring_exceeds(&gocroaring_process_budget, live)) ||
+	                    (budget != NULL && gocroaring_exceeds(budget, used)))) {
+		gocroaring_uncharge(budget, counted, size);
+		longjmp(try->env, 1);
+	}
+	if (counted) {
+		size_t peak = atomic_load(&gocroaring_peak_bytes);
+		while (live > peak && !atomic_compare_exchange_weak(&gocroaring_peak_bytes, &peak, live)) {
+		}
+		gocroaring_maybe_notify(live);
+	}
+}
+
+// gocroaring_out_of_memory is called when malloc fails
+static void gocroaring_out_of_memory(gocroaring_budget_t *budget, bool counted, size_t size) {
+	gocroaring_uncharge(budget, counted, size);
+	if (gocroaring_current_try != NULL) {
+		longjmp(gocroaring_current_try->env, 1);
+	}
+}
+
+static void gocroaring_budget_release(gocroaring_budget_t *budget) {
+	if (atomic_fetch_sub(&budget->refs, 1) == 1) {
+		free(budget);
+	}
+}
+
+static void gocroaring_link(gocroaring_extension_t *e) {
+	gocroaring_try_t *try = e->owner;
+	e->prev = NULL;
+	e->next = try->blocks;
+	if (e->next != NULL) {
+		e->next->prev = e;
+	}
+	try->blocks = e;
+}
+
+static void gocroaring_unlink(gocroaring_extension_t *e) {
+	if (e->prev != NULL) {
+		e->prev->next = e->next;
+	} else {
+		e->owner->blocks = e->next;
+	}
+	if (e->next != NULL) {
+		e->next->prev = e->prev;
+	}
+}
+
+static void *gocroaring_new_block(size_t alignment, size_t size, bool zero) {
+	gocroaring_try_t *try = gocroaring_current_try;
+	gocroaring_budget_t *budget = NULL;
+	if (try != NULL && try->budget != &gocroaring_process_budget) {
+		budget = try->budget;
+	}
+	bool counted = atomic_load_explicit(&gocroaring_accounting, memory_order_relaxed);
+	if (size > SIZE_MAX / 2) {
+		gocroaring_out_of_memory(budget, counted, 0);
+		return NULL;
+	}
+	if (try != NULL || counted) {
+		gocroaring_charge(budget, counted, size);
+	}
+	size_t header = sizeof(gocroaring_block_t);
+	if (try != NULL) {
+		header += sizeof(gocroaring_extension_t);
+	}
+	size_t extra = header;
+	if (alignment > _Alignof(max_align_t)) {
+		extra += alignment;
+	}
+	char *raw = zero ? calloc(1, extra + size) : malloc(extra + size);
+	if (raw == NULL) {
+		gocroaring_out_of_memory(budget, counted, size);
+		return NULL;
+	}
+	char *p = raw + header;
+	if (alignment > _Alignof(max_align_t)) {
+		p = (char *)(((uintptr_t)p + alignment - 1) & ~(uintptr_t)(alignment - 1));
+	}
+	gocroaring_block_t *b = (gocroaring_block_t *)p - 1;
+	b->size = size;
+	b->offset = (uint32_t)(p - raw);
+	b->counted = counted;
+	b->extended = try != NULL;
+	if (try != NULL) {
+		gocroaring_extension_t *e = gocroaring_extension(b);
+		e->owner = try;
+		e->budget = budget;
+		gocroaring_link(e);
+		if (budget != NULL) {
+			atomic_fetch_add(&budget->refs, 1);
+		}
+	}
+	if (counted) {
+		atomic_fetch_add(&gocroaring_live_allocations, 1);
+		atomic_fetch_add(&gocroaring_total_allocations, 1);
+	}
+	return p;
+}
+
+static void gocroaring_free_block(gocroaring_block_t *b) {
+	gocroaring_budget_t *budget = NULL;
+	if (b->extended) {
+		gocroaring_extension_t *e = gocroaring_extension(b);
+		if (e->owner != NULL) {
+			gocroaring_unlink(e);
+		}
+		budget = e->budget;
+	}
+	if (budget != NULL || b->counted) {
+		gocroaring_uncharge(budget, b->counted, b->size);
+	}
+	if (b->counted) {
+		atomic_fetch_sub(&gocroaring_live_allocations, 1);
+	}
+	free((char *)(b + 1) - b->offset);
+	if (budget != NULL) {
+		gocroaring_budget_release(budget);
+	}
+}
+
+static void *gocroaring_malloc(size_t size) {
+	return gocroaring_new_block(0, size, false);
+}
+
+static void *gocroaring_calloc(size_t count, size_t size) {
+	if (size != 0 && count > SIZE_MAX / size) {
+		return NULL;
+	}
+	return gocroaring_new_block(0, count * size, true);
+}
+
+static void *gocroaring_aligned_malloc(size_t alignment, size_t size) {
+	return gocroaring_new_block(alignment, size, false);
+}
+
+static void gocroaring_free(void *ptr) {
+	if (ptr != NULL) {
+		gocroaring_free_block((gocroaring_block_t *)ptr - 1);
+	}
+}
+
+static void *gocroaring_realloc(void *ptr, size_t size) {
+	if (ptr == NULL) {
+		return gocroaring_malloc(size);
+	}
+	gocroaring_block_t *b = (gocroaring_block_t *)ptr - 1;
+	gocroaring_budget_t *budget = b->extended ? gocroaring_extension(b)->budget : NULL;
+	bool counted = b->counted;
+	size_t oldsize = b->size;
+	if (size > SIZE_MAX / 2) {
+		gocroaring_out_of_memory(budget, counted, 0);
+		return NULL;
+	}
+	if (size > oldsize && (budget != NULL || counted)) {
+		gocroaring_charge(budget, counted, size - oldsize);
+	}
+	// realloc is only used on blocks from malloc and calloc, which are not realigned
+	size_t offset = b->offset;
+	char *raw = (char *)ptr - offset;
+	char *nraw = realloc(raw, offset + size);
+	if (nraw == NULL) {
+		if (size > oldsize) {
+			gocroaring_out_of_memory(budget, counted, size - oldsize);
+		}
+		return NULL;
+	}
+	if (size < oldsize && (budget != NULL || counted)) {
+		gocroaring_uncharge(budget, counted, oldsize - size);
+	}
+	gocroaring_block_t *nb = (gocroaring_block_t *)(nraw + offset) - 1;
+	nb->size = size;
+	if (nb->extended && nraw != raw) {
+		// the block moved, fix the list of the pending Try call
+		gocroaring_extension_t *e = gocroaring_extension(nb);
+		if (e->owner != NULL) {
+			if (e->prev != NULL) {
+				e->prev->next = e;
+			} else {
+				e->owner->blocks = e;
+			}
+			if (e->next != NULL) {
+				e->next->prev = e;
+			}
+		}
+	}
+	return nraw + offset;
+}
+
+static void gocroaring_aligned_free(void *ptr) {
+	gocroaring_free(ptr);
+}
+
+// The hook must be in place before CRoaring allocates anything, since blocks from
+// the default allocator cannot be released by the counting one.
+__attribute__((constructor)) static void gocroaring_init_memory_hook(void) {
+	roaring_memory_t hook = {
+		.malloc = gocroaring_malloc,
+		.realloc = gocroaring_realloc,
+		.calloc = gocroaring_calloc,
+		.free = gocroaring_free,
+		.aligned_malloc = gocroaring_aligned_malloc,
+		.aligned_free = gocroaring_aligned_free,
+	};
+	roaring_init_memory_hook(hook);
+}
+
+static void gocroaring_begin_try(gocroaring_try_t *try, gocroaring_budget_t *budget) {
+	try->budget = budget;
+	try->blocks = NULL;
+	try->previous = gocroaring_current_try;
+	gocroaring_current_try = try;
+}
+
+// gocroaring_end_try frees what the call allocated if it failed, otherwise it lets the blocks go
+static void gocroaring_end_try(gocroaring_try_t *try, bool failed) {
+	gocroaring_current_try = try->previous;
+	while (try->blocks != NULL) {
+		gocroaring_extension_t *e = try->blocks;
+		if (failed) {
+			gocroaring_free_block(gocroaring_extended_block(e));
+		} else {
+			try->blocks = e->next;
+			e->owner = NULL;
+		}
+	}
+}
+
+// GOCROARING_TRY defines the body of a function returning what call returns, or NULL
+// if that would exceed the budget
+#define GOCROARING_TRY(budget, call)                   \
+	gocroaring_try_t try;                              \
+	roaring_bitmap_t *volatile answer = NULL;          \
+	gocroaring_begin_try(&try, (budget));              \
+	if (setjmp(try.env) == 0) {                        \
+		answer = (call);                               \
+	}                                                  \
+	gocroaring_end_try(&try, answer == NULL);          \
+	return answer;
+
+static roaring_bitmap_t *gocroaring_try_copy(gocroaring_budget_t *budget, const roaring_bitmap_t *r) {
+	GOCROARING_TRY(budget, roaring_bitmap_copy(r))
+}
+
+static roaring_bitmap_t *gocroaring_try_or(gocroaring_budget_t *budget, const roaring_bitmap_t *x1, const roaring_bitmap_t *x2) {
+	GOCROARING_TRY(budget, roaring_bitmap_or(x1, x2))
+}
+
+static roaring_bitmap_t *gocroaring_try_and(gocroaring_budget_t *budget, const roaring_bitmap_t *x1, const roaring_bitmap_t *x2) {
+	GOCROARING_TRY(budget, roaring_bitmap_and(x1, x2))
+}
+
+static roaring_bitmap_t *gocroaring_try_xor(gocroaring_budget_t *budget, const roaring_bitmap_t *x1, const roaring_bitmap_t *x2) {
+	GOCROARING_TRY(budget, roaring_bitmap_xor(x1, x2))
+}
+
+static roaring_bitmap_t *gocroaring_try_andnot(gocroaring_budget_t *budget, const roaring_bitmap_t *x1, const roaring_bitmap_t *x2) {
+	GOCROARING_TRY(budget, roaring_bitmap_andnot(x1, x2))
+}
+
+static roaring_bitmap_t *gocroaring_try_flip(gocroaring_budget_t *budget, const roaring_bitmap_t *r, uint64_t start, uint64_t end) {
+	GOCROARING_TRY(budget, roaring_bitmap_flip(r, start, end))
+}
+
+static roaring_bitmap_t *gocroaring_try_or_many(gocroaring_budget_t *budget, size_t number, const roaring_bitmap_t **x) {
+	GOCROARING_TRY(budget, roaring_bitmap_or_many(number, x))
+}
+
+static gocroaring_budget_t *gocroaring_budget_create(size_t limit) {
+	gocroaring_budget_t *budget = malloc(sizeof(gocroaring_budget_t));
+	if (budget != NULL) {
+		atomic_init(&budget->used, 0);
+		atomic_init(&budget->limit, limit);
+		atomic_init(&budget->refs, 1);
+	}
+	return budget;
+}
+
+static gocroaring_budget_t *gocroaring_get_process_budget(void) {
+	return &gocroaring_process_budget;
+}
+
+static size_t gocroaring_budget_used(gocroaring_budget_t *budget) {
+	return atomic_load(&budget->used);
+}
+
+static size_t gocroaring_budget_limit(gocroaring_budget_t *budget) {
+	return atomic_load(&budget->limit);
+}
+
+static void gocroaring_budget_set_limit(gocroaring_budget_t *budget, size_t limit) {
+	atomic_store(&budget->limit, limit);
+}
+
+typedef struct {
+	uint64_t live_bytes;
+	uint64_t peak_bytes;
+	uint64_t live_allocations;
+	uint64_t total_allocations;
+} gocroaring_memory_stats_t;
+
+static gocroaring_memory_stats_t gocroaring_memory_stats(void) {
+	gocroaring_memory_stats_t stats = {
+		atomic_load(&gocroaring_process_budget.used),
+		atomic_load(&gocroaring_peak_bytes),
+		atomic_load(&gocroaring_live_allocations),
+		atomic_load(&gocroaring_total_allocations),
+	};
+	return stats;
+}
+
+static void gocroaring_enable_accounting(void) {
+	atomic_store(&gocroaring_accounting, true);
+}
+
+static void gocroaring_set_notify_step(size_t step) {
+	atomic_store(&gocroaring_notified_bytes, atomic_load(&gocroaring_process_budget.used));
+	atomic_store(&gocroaring_notify_step, step);
+}
+*/
+import "C"
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"unsafe"
+)
+
+// MemoryStatistics describes the memory allocated by CRoaring, which the Go runtime
+// (runtime.MemStats, GOMEMLIMIT) does not see. Only the memory allocated since
+// EnableMemoryAccounting was called is counted.
+type MemoryStatistics struct {
+	LiveBytes        uint64 // bytes currently allocated
+	PeakBytes        uint64 // largest value LiveBytes ever reached
+	LiveAllocations  uint64 // blocks currently allocated
+	TotalAllocations uint64 // blocks allocated since the program started
+}
+
+// EnableMemoryAccounting makes CRoaring count the memory it allocates for all bitmaps, as reported
+// by MemoryStats. It is off by default since every allocation then updates counters shared by all
+// threads. Memory allocated before the call is not counted. SetMemoryCallback, SetTotalMemoryLimit
+// and SetMemoryBudget turn it on.
+func EnableMemoryAccounting() {
+	C.gocroaring_enable_accounting()
+}
+
+// MemoryStats returns statistics about the memory allocated by CRoaring for all bitmaps,
+// once EnableMemoryAccounting was called (otherwise they are zero).
+func MemoryStats() MemoryStatistics {
+	stats := C.gocroaring_memory_stats()
+	return MemoryStatistics{
+		LiveBytes:        uint64(stats.live_bytes),
+		PeakBytes:        uint64(stats.peak_bytes),
+		LiveAllocations:  uint64(stats.live_allocations),
+		TotalAllocations: uint64(stats.total_allocations),
+	}
+}
+
+var memoryCallback struct {
+	sync.RWMutex
+	f func(MemoryStatistics)
+}
+
+// SetMemoryCallback registers f to be called whenever the memory allocated by CRoaring grew or
+// shrank by at least step bytes since the previous call. f runs synchronously while CRoaring
+// allocates or frees memory, so it should be quick and must not use bitmaps.
+// Passing a nil f (or a zero step) removes the callback.
+func SetMemoryCallback(step uint64, f func(MemoryStatistics)) {
+	if f == nil {
+		step = 0
+	} else {
+		EnableMemoryAccounting()
+	}
+	memoryCallback.Lock()
+	memoryCallback.f = f
+	memoryCallback.Unlock()
+	C.gocroaring_set_notify_step(C.size_t(step))
+}
+
+// SetTotalMemoryLimit keeps the Go memory limit (see runtime/debug.SetMemoryLimit) at limit minus
+// the memory allocated by CRoaring, so that the Go heap and the bitmaps together stay within limit.
+// The Go limit is adjusted every time the memory allocated by CRoaring moves by step bytes.
+// This replaces the callback set by SetMemoryCallback. A negative limit stops the adjustments.
+func SetTotalMemoryLimit(limit int64, step uint64) {
+	if limit < 0 {
+		SetMemoryCallback(0, nil)
+		return
+	}
+	EnableMemoryAccounting()
+	adjust := func(stats MemoryStatistics) {
+		goLimit := limit - int64(stats.LiveBytes)
+		if goLimit < 0 {
+			goLimit = 0
+		}
+		debug.SetMemoryLimit(goLimit)
+	}
+	adjust(MemoryStats())
+	SetMemoryCallback(step, adjust)
+}
+
+// Budget limits the memory allocated by CRoaring on behalf of the Try functions called on it.
+// The memory of the bitmaps created this way stays charged to the budget until they are freed,
+// so that a budget can account for the memory used by a query, a tenant or a cache. When such a
+// bitmap is modified later on, the blocks allocated by the Try call stay charged to the budget as
+// they grow, even past its limit, while new containers are only charged to the process budget
+// (see SetMemoryBudget), which every allocation counts against once the accounting is on.
+type Budget struct {
+	cpointer *C.gocroaring_budget_t
+}
+
+var processBudget = &Budget{C.gocroaring_get_process_budget()}
+
+func freeBudget(b *Budget) {
+	C.gocroaring_budget_release(b.cpointer)
+}
+
+// NewBudget creates a Budget allowing limit bytes, or any amount if limit is zero.
+// This function may panic if the allocation failed.
+func NewBudget(limit uint64) *Budget {
+	b := &Budget{C.gocroaring_budget_create(C.size_t(limit))}
+	if b.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	runtime.SetFinalizer(b, freeBudget)
+	return b
+}
+
+// SetMemoryBudget limits the memory allocated by CRoaring for all bitmaps to limit bytes
+// (zero means no limit). Only the Try functions fail when the limit would be exceeded:
+// other functions allocate regardless, but their memory counts against the limit.
+// A limit turns on the accounting, see EnableMemoryAccounting.
+func SetMemoryBudget(limit uint64) {
+	if limit != 0 {
+		EnableMemoryAccounting()
+	}
+	processBudget.SetLimit(limit)
+}
+
+// Used returns the number of bytes allocated for the bitmaps charged to the budget
+func (b *Budget) Used() uint64 {
+	answer := uint64(C.gocroaring_budget_used(b.cpointer))
+	runtime.KeepAlive(b)
+	return answer
+}
+
+// Limit returns the number of bytes allowed by the budget, zero means no limit
+func (b *Budget) Limit() uint64 {
+	answer := uint64(C.gocroaring_budget_limit(b.cpointer))
+	runtime.KeepAlive(b)
+	return answer
+}
+
+// SetLimit changes the number of bytes allowed by the budget, zero means no limit.
+// Bitmaps already charged to the budget are not affected.
+func (b *Budget) SetLimit(limit uint64) {
+	C.gocroaring_budget_set_limit(b.cpointer, C.size_t(limit))
+	runtime.KeepAlive(b)
+}
+
+// tryBitmap wraps the result of a Try call
+func tryBitmap(cpointer *C.struct_roaring_bitmap_s) (*Bitmap, error) {
+	if cpointer == nil {
+		return nil, ErrOutOfMemory
+	}
+	b := &Bitmap{cpointer: cpointer}
+	setFinalizer(b)
+	return b, nil
+}
+
+// TryClone creates a copy of the bitmap, charged to the budget
+func (b *Budget) TryClone(x ReadOnlyBitmap) (*Bitmap, error) {
+	answer := C.gocroaring_try_copy(b.cpointer, x.cbitmap("TryClone"))
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(x)
+	return tryBitmap(answer)
+}
+
+// TryOr computes the union between two bitmaps, charged to the budget
+func (b *Budget) TryOr(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	answer := C.gocroaring_try_or(b.cpointer, x1.cbitmap("TryOr"), x2.cbitmap("TryOr"))
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	return tryBitmap(answer)
+}
+
+// TryAnd computes the intersection between two bitmaps, charged to the budget
+func (b *Budget) TryAnd(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	answer := C.gocroaring_try_and(b.cpointer, x1.cbitmap("TryAnd"), x2.cbitmap("TryAnd"))
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	return tryBitmap(answer)
+}
+
+// TryXor computes the symmetric difference between two bitmaps, charged to the budget
+func (b *Budget) TryXor(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	answer := C.gocroaring_try_xor(b.cpointer, x1.cbitmap("TryXor"), x2.cbitmap("TryXor"))
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	return tryBitmap(answer)
+}
+
+// TryAndNot computes the difference between two bitmaps, charged to the budget
+func (b *Budget) TryAndNot(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	answer := C.gocroaring_try_andnot(b.cpointer, x1.cbitmap("TryAndNot"), x2.cbitmap("TryAndNot"))
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(x1)
+	runtime.KeepAlive(x2)
+	return tryBitmap(answer)
+}
+
+// TryFlip negates the bits in the given range (i.e., [rangeStart,rangeEnd)) of a copy of the bitmap, charged to the budget
+func (b *Budget) TryFlip(bm ReadOnlyBitmap, rangeStart, rangeEnd uint64) (*Bitmap, error) {
+	answer := C.gocroaring_try_flip(b.cpointer, bm.cbitmap("TryFlip"), C.uint64_t(rangeStart), C.uint64_t(rangeEnd))
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(bm)
+	return tryBitmap(answer)
+}
+
+// TryFastOr computes the union between many bitmaps quickly, charged to the budget
+func (b *Budget) TryFastOr(bitmaps ...*Bitmap) (*Bitmap, error) {
+	po := make([]*C.struct_roaring_bitmap_s, len(bitmaps))
+	for i, v := range bitmaps {
+		po[i] = v.cbitmap("TryFastOr")
+	}
+	var x **C.struct_roaring_bitmap_s // roaring_bitmap_or_many creates an empty bitmap when there are none
+	if len(po) > 0 {
+		x = (**C.struct_roaring_bitmap_s)(unsafe.Pointer(&po[0]))
+	}
+	answer := C.gocroaring_try_or_many(b.cpointer, C.size_t(len(po)), x)
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(bitmaps)
+	runtime.KeepAlive(po)
+	return tryBitmap(answer)
+}
+
+// TryClone creates a copy of the bitmap, or returns ErrOutOfMemory if that would exceed the memory budget
+func (rb *Bitmap) TryClone() (*Bitmap, error) {
+	rb.check("TryClone")
+	return processBudget.TryClone(rb)
+}
+
+// TryClone creates a mutable copy of the bitmap, or returns ErrOutOfMemory if that would exceed the memory budget
+func (ib *readOnlyBitmap) TryClone() (*Bitmap, error) {
+	return processBudget.TryClone(ib)
+}
+
+// TryOr computes the union between two bitmaps, or returns ErrOutOfMemory if that would exceed the memory budget
+func TryOr(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	return processBudget.TryOr(x1, x2)
+}
+
+// TryAnd computes the intersection between two bitmaps, or returns ErrOutOfMemory if that would exceed the memory budget
+func TryAnd(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	return processBudget.TryAnd(x1, x2)
+}
+
+// TryXor computes the symmetric difference between two bitmaps, or returns ErrOutOfMemory if that would exceed the memory budget
+func TryXor(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	return processBudget.TryXor(x1, x2)
+}
+
+// TryAndNot computes the difference between two bitmaps, or returns ErrOutOfMemory if that would exceed the memory budget
+func TryAndNot(x1, x2 ReadOnlyBitmap) (*Bitmap, error) {
+	return processBudget.TryAndNot(x1, x2)
+}
+
+// TryFlip negates the bits in the given range (i.e., [rangeStart,rangeEnd)) of a copy of the bitmap,
+// or returns ErrOutOfMemory if that would exceed the memory budget
+func TryFlip(bm ReadOnlyBitmap, rangeStart, rangeEnd uint64) (*Bitmap, error) {
+	return processBudget.TryFlip(bm, rangeStart, rangeEnd)
+}
+
+// TryFastOr computes the union between many bitmaps quickly, or returns ErrOutOfMemory if that would exceed the memory budget
+func TryFastOr(bitmaps ...*Bitmap) (*Bitmap, error) {
+	return processBudget.TryFastOr(bitmaps...)
+}
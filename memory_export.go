@@ -0,0 +1,13 @@
+package gocroaring
+
+import "C"
+
+//export gocroaringMemoryCallback
+func gocroaringMemoryCallback() {
+	memoryCallback.RLock()
+	f := memoryCallback.f
+	memoryCallback.RUnlock()
+	if f != nil {
+		f(MemoryStats())
+	}
+}
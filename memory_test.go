@@ -0,0 +1,219 @@
+package gocroaring
+
+import (
+	"runtime"
+	"runtime/debug"
+	"testing"
+)
+
+// bitsetBitmap returns a bitmap made of n bitset containers, taking about n*8kB
+func bitsetBitmap(n int) *Bitmap {
+	values := make([]uint32, 0, n<<15)
+	for i := 0; i < n<<16; i += 2 {
+		values = append(values, uint32(i))
+	}
+	return New(values...)
+}
+
+// collectBitmaps runs the garbage collector and waits for the finalizers, so that
+// unreachable bitmaps do not release memory while a test measures it
+func collectBitmaps() {
+	for {
+		live := MemoryStats().LiveBytes
+		done := make(chan bool)
+		finalizeLater(done)
+		runtime.GC()
+		<-done
+		if MemoryStats().LiveBytes == live {
+			return
+		}
+	}
+}
+
+// sentinel is large enough not to be batched with other small objects, which might never be finalized
+type sentinel struct {
+	done chan bool
+	_    [64]byte
+}
+
+//go:noinline
+func finalizeLater(done chan bool) {
+	runtime.SetFinalizer(&sentinel{done: done}, func(s *sentinel) { close(s.done) })
+}
+
+func TestMemoryStats(t *testing.T) {
+	EnableMemoryAccounting()
+	rb := New()
+	for i := uint32(0); i < 100; i++ {
+		rb.Add(i * 1000000) // many array containers
+	}
+	bitsets := bitsetBitmap(16)
+	rb.Or(bitsets)
+	bitsets.Free()
+	collectBitmaps()
+	before := MemoryStats()
+	clone := rb.Clone()
+	during := MemoryStats()
+	if during.LiveBytes < before.LiveBytes+1<<17 {
+		t.Errorf("expected at least 128kB more, got %d then %d", before.LiveBytes, during.LiveBytes)
+	}
+	if during.TotalAllocations <= before.TotalAllocations || during.PeakBytes < during.LiveBytes {
+		t.Errorf("bad statistics %+v", during)
+	}
+	clone.Free()
+	after := MemoryStats()
+	if after.LiveBytes > during.LiveBytes-1<<17 {
+		t.Errorf("expected the memory to be released, got %d then %d", during.LiveBytes, after.LiveBytes)
+	}
+	if after.PeakBytes < during.LiveBytes {
+		t.Error("the peak went down")
+	}
+	rb.Free()
+}
+
+func TestMemoryCallback(t *testing.T) {
+	collectBitmaps()
+	calls := 0
+	var last MemoryStatistics
+	SetMemoryCallback(1<<16, func(stats MemoryStatistics) {
+		calls++
+		last = stats
+	})
+	rb := bitsetBitmap(64)
+	SetMemoryCallback(0, nil)
+	if calls == 0 {
+		t.Fatal("the callback was not called")
+	}
+	if last.LiveBytes == 0 {
+		t.Error("bad statistics")
+	}
+	rb.Free()
+	calls = 0
+	bitsetBitmap(64).Free()
+	if calls != 0 {
+		t.Error("the callback was not removed")
+	}
+}
+
+func TestSetTotalMemoryLimit(t *testing.T) {
+	collectBitmaps()
+	defer debug.SetMemoryLimit(debug.SetMemoryLimit(-1))
+	const limit = 1 << 40
+	SetTotalMemoryLimit(limit, 1<<16)
+	defer SetTotalMemoryLimit(-1, 0)
+	rb := bitsetBitmap(64)
+	got := debug.SetMemoryLimit(-1)
+	if expected := limit - int64(MemoryStats().LiveBytes); got > expected+1<<16 || got < expected-1<<16 {
+		t.Errorf("expected a Go memory limit near %d, got %d", expected, got)
+	}
+	rb.Free()
+}
+
+func TestBudget(t *testing.T) {
+	x1 := bitsetBitmap(32)
+	x2 := New()
+	x2.AddRange(1<<24, 1<<26)
+	x2.Add(1, 2, 1<<30, 1<<30+5)
+	budget := NewBudget(0)
+	union, err := budget.TryOr(x1, x2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !union.Equals(Or(x1, x2)) {
+		t.Error("bad TryOr")
+	}
+	used := budget.Used()
+	if used < 32<<13 {
+		t.Errorf("expected at least 256kB to be charged, got %d", used)
+	}
+	union.Free()
+	if budget.Used() != 0 {
+		t.Errorf("expected the budget to be released, got %d", budget.Used())
+	}
+
+	// a container allocated by the Try call stays charged to the budget as it grows
+	small, err := budget.TryOr(New(1), New(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	used = budget.Used()
+	for i := uint32(3); i < 2000; i++ {
+		small.Add(i)
+	}
+	if budget.Used() < used+2*2000 {
+		t.Errorf("expected the growth to be charged to the budget, got %d then %d", used, budget.Used())
+	}
+	small.Free()
+	if budget.Used() != 0 {
+		t.Errorf("expected the budget to be released, got %d", budget.Used())
+	}
+
+	budget.SetLimit(100000)
+	if budget.Limit() != 100000 {
+		t.Error("bad limit")
+	}
+	for _, f := range []func() (*Bitmap, error){
+		func() (*Bitmap, error) { return budget.TryClone(x1) },
+		func() (*Bitmap, error) { return budget.TryOr(x1, x2) },
+		func() (*Bitmap, error) { return budget.TryXor(x2, x1) },
+		func() (*Bitmap, error) { return budget.TryAndNot(x1, x2) },
+		func() (*Bitmap, error) { return budget.TryFlip(x2, 0, 1<<32) },
+		func() (*Bitmap, error) { return budget.TryFastOr(x2, x1, x2) },
+	} {
+		answer, err := f()
+		if err != ErrOutOfMemory || answer != nil {
+			t.Errorf("expected ErrOutOfMemory, got %v", err)
+		}
+		if budget.Used() != 0 {
+			t.Errorf("expected a failed call to release its memory, got %d", budget.Used())
+		}
+	}
+	// small results fit
+	intersection, err := budget.TryAnd(x1, x2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !intersection.Equals(And(x1, x2)) {
+		t.Error("bad TryAnd")
+	}
+	intersection.Free()
+	empty, err := budget.TryFastOr()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !empty.IsEmpty() || budget.Used() == 0 {
+		t.Errorf("expected an empty bitmap charged to the budget, got %d bytes", budget.Used())
+	}
+	empty.Free()
+	if budget.Used() != 0 {
+		t.Errorf("expected the budget to be released, got %d", budget.Used())
+	}
+}
+
+func TestMemoryBudget(t *testing.T) {
+	x1 := bitsetBitmap(32)
+	x2 := New(1, 2, 3)
+	defer SetMemoryBudget(0)
+	SetMemoryBudget(MemoryStats().LiveBytes + 100000)
+	if _, err := TryOr(x1, New(1<<30)); err != ErrOutOfMemory {
+		t.Errorf("expected ErrOutOfMemory, got %v", err)
+	}
+	if _, err := x1.TryClone(); err != ErrOutOfMemory {
+		t.Errorf("expected ErrOutOfMemory, got %v", err)
+	}
+	small, err := TryFastOr(x2, x2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !small.Equals(x2) {
+		t.Error("bad TryFastOr")
+	}
+	SetMemoryBudget(0)
+	big, err := x1.TryClone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !big.Equals(x1) {
+		t.Error("bad TryClone")
+	}
+}
@@ -0,0 +1,77 @@
+//go:build unix
+
+package gocroaring
+
+/*
+#include "roaring.h"
+
+*/
+import "C"
+import (
+	"fmt"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// OpenFrozenFile maps length bytes of the file, starting at offset, and reads them as a frozen
+// bitmap (see WriteFrozen). A length of 0 maps the file up to its end. The offset must be a
+// multiple of 32 since the frozen format requires aligned data. The bitmap is read-only, call
+// Close once you are done to unmap the file (or Clone it to get a mutable copy).
+func OpenFrozenFile(path string, offset, length int64) (*ImmutableBitmap, error) {
+	if offset%32 != 0 {
+		return nil, fmt.Errorf("%w: frozen data must start at an offset that is a multiple of 32", ErrCorrupt)
+	}
+	return openMapped(path, offset, length, func(data []byte) *C.struct_roaring_bitmap_s {
+		return C.roaring_bitmap_frozen_view((*C.char)(unsafe.Pointer(&data[0])), C.size_t(len(data)))
+	})
+}
+
+// OpenPortableFile maps length bytes of the file, starting at offset, and reads them as a bitmap
+// in the portable format (see Write) without copying its containers. A length of 0 maps the file
+// up to its end, trailing bytes after the bitmap are ignored. The bitmap is read-only, as with
+// OpenFrozenFile.
+func OpenPortableFile(path string, offset, length int64) (*ImmutableBitmap, error) {
+	return openMapped(path, offset, length, func(data []byte) *C.struct_roaring_bitmap_s {
+		bchar := (*C.char)(unsafe.Pointer(&data[0]))
+		// roaring_bitmap_portable_deserialize_frozen trusts its input, so we check it first
+		if C.roaring_bitmap_portable_deserialize_size(bchar, C.size_t(len(data))) == 0 {
+			return nil
+		}
+		return C.roaring_bitmap_portable_deserialize_frozen(bchar)
+	})
+}
+
+// openMapped maps [offset, offset+length) of the file read-only and builds a view on it
+func openMapped(path string, offset, length int64, view func([]byte) *C.struct_roaring_bitmap_s) (*ImmutableBitmap, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if length == 0 {
+		length = fi.Size() - offset
+	}
+	if offset < 0 || length < 0 || offset+length > fi.Size() {
+		return nil, fmt.Errorf("%w: invalid range of the file", ErrCorrupt)
+	}
+	if length == 0 {
+		return nil, ErrEmpty
+	}
+	// mmap needs an offset that is a multiple of the page size
+	pageOffset := offset % int64(os.Getpagesize())
+	mapping, err := syscall.Mmap(int(f.Fd()), offset-pageOffset, int(length+pageOffset), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return nil, err
+	}
+	cpointer := view(mapping[pageOffset:])
+	if cpointer == nil {
+		syscall.Munmap(mapping)
+		return nil, ErrCorrupt
+	}
+	return newImmutableBitmap(cpointer, nil, func() error { return syscall.Munmap(mapping) }), nil
+}
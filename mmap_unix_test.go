@@ -0,0 +1,100 @@
+//go:build unix
+
+package gocroaring
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestOpenFrozenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bitmaps")
+	var content []byte
+	var offsets []int64
+	bitmaps := testBitmaps()
+	for _, rb := range bitmaps {
+		// the frozen format needs 32-byte aligned data
+		for len(content)%32 != 0 {
+			content = append(content, 0)
+		}
+		offsets = append(offsets, int64(len(content)))
+		buf := make([]byte, rb.FrozenSizeInBytes())
+		rb.WriteFrozen(buf)
+		content = append(content, buf...)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, rb := range bitmaps {
+		mb, err := OpenFrozenFile(path, offsets[i], int64(rb.FrozenSizeInBytes()))
+		if err != nil {
+			t.Fatal("OpenFrozenFile failed", err)
+		}
+		if !rb.Equals(mb) {
+			t.Error("Bad read")
+		}
+		if _, ok := interface{}(mb).(interface{ Add(x ...uint32) }); ok {
+			t.Error("a mapped bitmap should not be mutable")
+		}
+		if err := mb.Close(); err != nil {
+			t.Error("Close failed", err)
+		}
+		mb.Close() // closing twice is harmless
+		// the file is unmapped, so any use must panic rather than read it
+		expectPanic(t, "Contains", func() { mb.Contains(1) })
+		expectPanic(t, "Cardinality", func() { mb.Cardinality() })
+		expectPanic(t, "Iterator", func() { mb.Iterator() })
+		expectPanic(t, "Clone", func() { mb.Clone() })
+	}
+	last := bitmaps[len(bitmaps)-1]
+	mb, err := OpenFrozenFile(path, offsets[len(offsets)-1], 0)
+	if err != nil || !last.Equals(mb) {
+		t.Error("Bad read up to the end of the file", err)
+	}
+	mb = nil
+	runtime.GC() // the finalizer unmaps the file
+
+	if _, err := OpenFrozenFile(path, 1, 0); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt on a misaligned offset, got %v", err)
+	}
+	if _, err := OpenFrozenFile(path, 0, int64(len(content))+1); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt past the end of the file, got %v", err)
+	}
+	if _, err := OpenFrozenFile(filepath.Join(t.TempDir(), "missing"), 0, 0); err == nil {
+		t.Error("expected an error on a missing file")
+	}
+}
+
+func TestOpenPortableFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bitmaps")
+	content := []byte("header")
+	var offsets []int64
+	bitmaps := testBitmaps()
+	for _, rb := range bitmaps {
+		offsets = append(offsets, int64(len(content)))
+		content = rb.AppendTo(content)
+	}
+	if err := os.WriteFile(path, content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for i, rb := range bitmaps {
+		mb, err := OpenPortableFile(path, offsets[i], 0)
+		if err != nil {
+			t.Fatal("OpenPortableFile failed", err)
+		}
+		if !rb.Equals(mb) {
+			t.Error("Bad read")
+		}
+		mb.Free()
+	}
+	if _, err := OpenPortableFile(path, 0, 0); !errors.Is(err, ErrCorrupt) {
+		t.Errorf("expected ErrCorrupt on bad input, got %v", err)
+	}
+	if _, err := OpenPortableFile(path, int64(len(content)), 0); !errors.Is(err, ErrEmpty) {
+		t.Errorf("expected ErrEmpty at the end of the file, got %v", err)
+	}
+}
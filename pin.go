@@ -0,0 +1,19 @@
+//go:build go1.21
+
+package gocroaring
+
+import "runtime"
+
+// canPin tells whether pinBuffer can pin memory, so that views read their buffer in place
+const canPin = true
+
+// pinBuffer pins the object p points into, so that C memory may hold pointers to it,
+// and returns the function unpinning it
+func pinBuffer(p *byte) (unpin func() error, ok bool) {
+	var pinner runtime.Pinner
+	pinner.Pin(p)
+	return func() error {
+		pinner.Unpin()
+		return nil
+	}, true
+}
@@ -0,0 +1,11 @@
+//go:build !go1.21
+
+package gocroaring
+
+// canPin tells whether pinBuffer can pin memory, so that views read their buffer in place
+const canPin = false
+
+// pinBuffer cannot pin memory before Go 1.21, the buffers are copied instead
+func pinBuffer(p *byte) (unpin func() error, ok bool) {
+	return nil, false
+}
@@ -0,0 +1,109 @@
+package gocroaring
+
+/*
+#include "roaring.h"
+
+// gocroaring_pool_reset empties the bitmap. It keeps its array of containers for the next use,
+// unless the array has room for more than max_capacity containers: then it is freed.
+static void gocroaring_pool_reset(roaring_bitmap_t *r, int32_t max_capacity) {
+	// unlike roaring_bitmap_clear, removing everything does not free the array
+	roaring_bitmap_remove_range_closed(r, 0, UINT32_MAX);
+	if (r->high_low_container.allocation_size > max_capacity) {
+		roaring_bitmap_shrink_to_fit(r);
+	}
+}
+*/
+import "C"
+import (
+	"runtime"
+	"sync"
+)
+
+// Pool hands out empty bitmaps and takes them back once they are no longer needed, so that
+// scratch bitmaps reuse their C structures instead of being allocated and finalized again
+// and again. It is safe for concurrent use.
+type Pool struct {
+	mu          sync.Mutex
+	idle        []*Bitmap
+	capacity    uint32
+	maxCapacity uint32
+	maxIdle     int
+}
+
+// NewPool creates a pool of bitmaps with room for capacity containers (a container holds the
+// integers sharing their 16 high bits). To bound the memory kept by the pool, a bitmap given
+// back with room for more than maxCapacity containers releases its memory, and no more than
+// maxIdle bitmaps are kept: the others are freed.
+func NewPool(capacity, maxCapacity uint32, maxIdle int) *Pool {
+	return &Pool{capacity: capacity, maxCapacity: maxCapacity, maxIdle: maxIdle}
+}
+
+// Get returns an empty bitmap, which should be given back with Put once it is no longer needed.
+// This function may panic if the allocation failed.
+func (p *Pool) Get() *Bitmap {
+	p.mu.Lock()
+	if n := len(p.idle); n > 0 {
+		answer := p.idle[n-1]
+		p.idle[n-1] = nil
+		p.idle = p.idle[:n-1]
+		answer.pooled = false
+		p.mu.Unlock()
+		return answer
+	}
+	p.mu.Unlock()
+	answer := &Bitmap{cpointer: C.roaring_bitmap_create_with_capacity(C.uint32_t(p.capacity))}
+	if answer.cpointer == nil {
+		panic("C code returned a null pointer.")
+	}
+	setFinalizer(answer)
+	return answer
+}
+
+// Put empties the bitmap and gives it back to the pool. It must not be used afterwards.
+// Putting a bitmap that is already idle in the pool panics, since two calls to Get would then
+// hand it to two owners.
+func (p *Pool) Put(rb *Bitmap) {
+	rb.check("Put")
+	p.mu.Lock()
+	if rb.pooled {
+		p.mu.Unlock()
+		panic("gocroaring: Put called on a bitmap that is already in the pool")
+	}
+	// claim the bitmap, so that a concurrent Put of the same bitmap panics
+	rb.pooled = true
+	p.mu.Unlock()
+	maxCapacity := C.int32_t(p.maxCapacity)
+	if p.maxCapacity > 1<<16 {
+		maxCapacity = 1 << 16
+	}
+	C.gocroaring_pool_reset(rb.cpointer, maxCapacity)
+	runtime.KeepAlive(rb)
+	p.mu.Lock()
+	if len(p.idle) < p.maxIdle {
+		p.idle = append(p.idle, rb)
+		rb = nil
+	}
+	p.mu.Unlock()
+	if rb != nil {
+		rb.pooled = false
+		rb.Free()
+	}
+}
+
+// Len returns the number of idle bitmaps kept by the pool
+func (p *Pool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.idle)
+}
+
+// Release frees the idle bitmaps kept by the pool
+func (p *Pool) Release() {
+	p.mu.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.mu.Unlock()
+	for _, rb := range idle {
+		rb.Free()
+	}
+}
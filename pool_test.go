@@ -0,0 +1,83 @@
+package gocroaring
+
+import "testing"
+
+// sparseBitmap returns a bitmap made of n containers holding a single integer
+func sparseBitmap(n int) *Bitmap {
+	rb := New()
+	for i := 0; i < n; i++ {
+		rb.Add(uint32(i) << 16)
+	}
+	return rb
+}
+
+func TestPool(t *testing.T) {
+	pool := NewPool(4, 64, 2)
+	rb := pool.Get()
+	if !rb.IsEmpty() {
+		t.Error("expected an empty bitmap")
+	}
+	rb.AddRange(0, 1<<20)
+	pool.Put(rb)
+	again := pool.Get()
+	if again != rb {
+		t.Error("the bitmap was not reused")
+	}
+	if !again.IsEmpty() {
+		t.Error("the bitmap was not emptied")
+	}
+	again.Add(1, 2, 3)
+	if again.Cardinality() != 3 {
+		t.Error("bad reused bitmap")
+	}
+
+	bitmaps := []*Bitmap{again, pool.Get(), pool.Get()}
+	for _, rb := range bitmaps {
+		pool.Put(rb)
+	}
+	if pool.Len() != 2 {
+		t.Errorf("expected 2 idle bitmaps, got %d", pool.Len())
+	}
+	expectPanic(t, "Add", func() { bitmaps[2].Add(1) })
+	pool.Release()
+	if pool.Len() != 0 {
+		t.Error("the pool was not emptied")
+	}
+	expectPanic(t, "Add", func() { bitmaps[0].Add(1) })
+}
+
+func TestPoolDoublePut(t *testing.T) {
+	pool := NewPool(0, 64, 4)
+	rb := pool.Get()
+	pool.Put(rb)
+	expectPanic(t, "Put", func() { pool.Put(rb) })
+	if pool.Len() != 1 {
+		t.Errorf("expected 1 idle bitmap, got %d", pool.Len())
+	}
+	if first, second := pool.Get(), pool.Get(); first == second {
+		t.Error("the same bitmap was handed out twice")
+	}
+	// once taken out of the pool, the bitmap can be put back
+	pool.Put(rb)
+	pool.Release()
+}
+
+func TestPoolMaxCapacity(t *testing.T) {
+	EnableMemoryAccounting()
+	const containers = 4096
+	released := func(maxCapacity uint32) int64 {
+		pool := NewPool(0, maxCapacity, 1)
+		rb := sparseBitmap(containers)
+		collectBitmaps()
+		before := MemoryStats().LiveBytes
+		pool.Put(rb)
+		after := MemoryStats().LiveBytes
+		pool.Release()
+		return int64(before) - int64(after)
+	}
+	kept, shrunk := released(1<<16), released(16)
+	// the array of containers holds a pointer, a key and a type code per container
+	if shrunk-kept < containers*8 {
+		t.Errorf("expected the array of containers to be freed, released %d then %d bytes", kept, shrunk)
+	}
+}
@@ -0,0 +1,359 @@
+package gocroaring
+
+/*
+#include "roaring.h"
+
+*/
+import "C"
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+	"unsafe"
+)
+
+const (
+	serialCookieNoRunContainer = 12346
+	serialCookie               = 12347
+	frozenCookie               = 13766
+	noOffsetThreshold          = 4
+	arrayContainerMaxSize      = 4096
+	bitsetContainerSizeInBytes = 8192
+
+	// first byte of the native format
+	nativeArrayUint32 = 1
+	nativeContainer   = 2
+)
+
+// replace makes rb hold the given C bitmap, freeing the one it held before.
+// A zero Bitmap may be a field of the caller's struct, so its finalizer goes on a bitmapOwner.
+func (rb *Bitmap) replace(cpointer *C.struct_roaring_bitmap_s) {
+	if rb.cpointer == nil {
+		rb.cpointer = cpointer
+		rb.owner = &bitmapOwner{cpointer}
+		track(unsafe.Pointer(cpointer), kindBitmap)
+		runtime.SetFinalizer(rb.owner, freeOwner)
+		return
+	}
+	old := rb.cpointer
+	rb.cpointer = cpointer
+	if rb.owner != nil {
+		rb.owner.cpointer = cpointer
+	}
+	track(unsafe.Pointer(cpointer), kindBitmap)
+	untrack(unsafe.Pointer(old), false)
+	C.roaring_bitmap_free(old)
+}
+
+// AppendTo appends a serialized version of this bitmap to b and returns the extended slice
+func (rb *Bitmap) AppendTo(b []byte) []byte {
+	rb.check("AppendTo")
+	size := rb.SerializedSizeInBytes()
+	start := len(b)
+	if cap(b)-start < size {
+		nb := make([]byte, start, start+size)
+		copy(nb, b)
+		b = nb
+	}
+	b = b[:start+size]
+	rb.Write(b[start:]) // cannot fail, we made enough space
+	return b
+}
+
+// WriteTo writes a serialized version of this bitmap to the stream, it implements io.WriterTo
+func (rb *Bitmap) WriteTo(w io.Writer) (int64, error) {
+	rb.check("WriteTo")
+	n, err := w.Write(rb.AppendTo(nil))
+	return int64(n), err
+}
+
+// ReadFrom replaces the content of the bitmap with a serialized bitmap read from the stream,
+// it implements io.ReaderFrom. It reads exactly the bytes of one bitmap, so that several
+// bitmaps can be read one after the other from the same stream.
+func (rb *Bitmap) ReadFrom(r io.Reader) (int64, error) {
+	buf, err := readPortable(r)
+	if err != nil {
+		return int64(len(buf)), err
+	}
+	b, err := Read(buf)
+	if err != nil {
+		return int64(len(buf)), err
+	}
+	runtime.SetFinalizer(b, nil)
+	rb.replace(b.cpointer)
+	return int64(len(buf)), nil
+}
+
+// MarshalBinary returns the portable serialization of the bitmap, it implements encoding.BinaryMarshaler
+func (rb *Bitmap) MarshalBinary() ([]byte, error) {
+	if rb == nil {
+		return New().AppendTo(nil), nil
+	}
+	rb.check("MarshalBinary")
+	return rb.AppendTo(nil), nil
+}
+
+// UnmarshalBinary replaces the content of the bitmap with a portable serialization,
+// it implements encoding.BinaryUnmarshaler
+func (rb *Bitmap) UnmarshalBinary(data []byte) error {
+	if len(data) == 0 {
+		return ErrEmpty
+	}
+	b, err := Read(data)
+	if err != nil {
+		return err
+	}
+	runtime.SetFinalizer(b, nil)
+	rb.replace(b.cpointer)
+	return nil
+}
+
+// GobEncode implements gob.GobEncoder using the portable serialization
+func (rb *Bitmap) GobEncode() ([]byte, error) {
+	return rb.MarshalBinary()
+}
+
+// GobDecode implements gob.GobDecoder using the portable serialization
+func (rb *Bitmap) GobDecode(data []byte) error {
+	return rb.UnmarshalBinary(data)
+}
+
+// MarshalJSON encodes the bitmap as an array of integers, e.g. [1,2,3], it implements json.Marshaler.
+// See Base64Bitmap for a more compact encoding.
+func (rb *Bitmap) MarshalJSON() ([]byte, error) {
+	if rb == nil {
+		return []byte("null"), nil
+	}
+	rb.check("MarshalJSON")
+	return json.Marshal(rb.ToArray())
+}
+
+// UnmarshalJSON replaces the content of the bitmap with a JSON array of integers or a
+// base64-encoded portable serialization, it implements json.Unmarshaler
+func (rb *Bitmap) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var b []byte
+		if err := json.Unmarshal(data, &b); err != nil {
+			return err
+		}
+		return rb.UnmarshalBinary(b)
+	default:
+		var values []uint32
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		var cpointer *C.struct_roaring_bitmap_s
+		if len(values) > 0 {
+			cpointer = C.roaring_bitmap_of_ptr(C.size_t(len(values)), (*C.uint32_t)(unsafe.Pointer(&values[0])))
+		} else {
+			cpointer = C.roaring_bitmap_create()
+		}
+		if cpointer == nil {
+			panic("C code returned a null pointer.")
+		}
+		rb.replace(cpointer)
+		return nil
+	}
+}
+
+// Base64Bitmap is a Bitmap encoded in JSON as a string holding its base64-encoded portable
+// serialization, which is much more compact than an array of integers for large bitmaps.
+// Use *Base64Bitmap as the type of a struct field, or convert a *Bitmap: (*Base64Bitmap)(rb).
+// As with Bitmap, a field of type Base64Bitmap rather than a pointer is encoded as {}.
+// Both types decode either encoding.
+type Base64Bitmap Bitmap
+
+// Bitmap returns the bitmap itself, they share the same memory
+func (b *Base64Bitmap) Bitmap() *Bitmap {
+	return (*Bitmap)(b)
+}
+
+// MarshalJSON encodes the bitmap as a base64 string, it implements json.Marshaler
+func (b *Base64Bitmap) MarshalJSON() ([]byte, error) {
+	if b == nil {
+		return []byte("null"), nil
+	}
+	rb := b.Bitmap()
+	rb.check("MarshalJSON")
+	return json.Marshal(rb.AppendTo(nil))
+}
+
+// UnmarshalJSON replaces the content of the bitmap with a base64-encoded portable serialization
+// or a JSON array of integers, it implements json.Unmarshaler
+func (b *Base64Bitmap) UnmarshalJSON(data []byte) error {
+	return b.Bitmap().UnmarshalJSON(data)
+}
+
+// NativeSizeInBytes computes the size in bytes of the bitmap in the CRoaring native format
+func (rb *Bitmap) NativeSizeInBytes() int {
+	rb.check("NativeSizeInBytes")
+	answer := int(C.roaring_bitmap_size_in_bytes(rb.cpointer))
+	runtime.KeepAlive(rb)
+	return answer
+}
+
+// WriteNative writes a serialized version of this bitmap to stream in the CRoaring native format
+// (you should have enough space). This format is not compatible with the Java and Go libraries,
+// prefer Write unless you need to talk to C code using roaring_bitmap_deserialize.
+func (rb *Bitmap) WriteNative(b []byte) error {
+	rb.check("WriteNative")
+	if len(b) < rb.NativeSizeInBytes() {
+		return ErrBufferTooSmall
+	}
+	bchar := (*C.char)(unsafe.Pointer(&b[0]))
+	C.roaring_bitmap_serialize(rb.cpointer, bchar)
+	runtime.KeepAlive(b)
+	runtime.KeepAlive(rb)
+	return nil
+}
+
+// ReadNative reads a bitmap serialized in the CRoaring native format, as written by WriteNative
+// or roaring_bitmap_serialize (you need to call Free on it once you are done)
+func ReadNative(b []byte) (*Bitmap, error) {
+	if len(b) == 0 {
+		return nil, ErrEmpty
+	}
+	answer := &Bitmap{cpointer: C.roaring_bitmap_deserialize_safe(unsafe.Pointer(&b[0]), C.size_t(len(b)))}
+	runtime.KeepAlive(b)
+	if answer.cpointer == nil {
+		return nil, ErrCorrupt
+	}
+	setFinalizer(answer)
+	return answer, nil
+}
+
+// ReadAny reads a bitmap in the portable, native or frozen format, detecting the format
+// from the data itself. The result never references b, even for frozen input.
+func ReadAny(b []byte) (*Bitmap, error) {
+	switch {
+	case len(b) == 0:
+		return nil, ErrEmpty
+	case isPortable(b):
+		return Read(b)
+	case isNative(b):
+		return ReadNative(b)
+	case isFrozen(b):
+		view, err := ReadFrozenView(b)
+		if err != nil {
+			return nil, err
+		}
+		answer := view.Clone()
+		view.Free()
+		return answer, nil
+	}
+	return nil, fmt.Errorf("%w: unknown format", ErrCorrupt)
+}
+
+// isPortable returns true if b holds exactly one bitmap in the portable format
+func isPortable(b []byte) bool {
+	if len(b) < 4 {
+		return false
+	}
+	cookie := binary.LittleEndian.Uint32(b)
+	if cookie&0xFFFF != serialCookie && cookie != serialCookieNoRunContainer {
+		return false
+	}
+	size := int(C.roaring_bitmap_portable_deserialize_size((*C.char)(unsafe.Pointer(&b[0])), C.size_t(len(b))))
+	runtime.KeepAlive(b)
+	return size == len(b)
+}
+
+// isNative returns true if b holds exactly one bitmap in the native format
+func isNative(b []byte) bool {
+	if len(b) < 5 {
+		return false
+	}
+	switch b[0] {
+	case nativeArrayUint32:
+		return uint64(len(b)) == 5+4*uint64(binary.LittleEndian.Uint32(b[1:]))
+	case nativeContainer:
+		return isPortable(b[1:])
+	}
+	return false
+}
+
+// isFrozen returns true if b ends with the header of the frozen format
+func isFrozen(b []byte) bool {
+	return len(b) >= 4 && binary.LittleEndian.Uint32(b[len(b)-4:])&0x7FFF == frozenCookie
+}
+
+// readPortable reads one bitmap in the portable format from r, without reading past its end
+func readPortable(r io.Reader) ([]byte, error) {
+	var buf []byte
+	next := func(n int) ([]byte, error) {
+		start := len(buf)
+		buf = append(buf, make([]byte, n)...)
+		_, err := io.ReadFull(r, buf[start:])
+		if err == io.EOF && start > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		return buf[start:], err
+	}
+
+	header, err := next(4)
+	if err != nil {
+		return buf, err
+	}
+	cookie := binary.LittleEndian.Uint32(header)
+	var size int
+	var runs []byte
+	switch {
+	case cookie&0xFFFF == serialCookie:
+		size = int(cookie>>16) + 1
+		if runs, err = next((size + 7) / 8); err != nil {
+			return buf, err
+		}
+	case cookie == serialCookieNoRunContainer:
+		b, err := next(4)
+		if err != nil {
+			return buf, err
+		}
+		size = int(binary.LittleEndian.Uint32(b))
+		if size > 1<<16 {
+			return buf, ErrCorrupt
+		}
+	default:
+		return buf, ErrCorrupt
+	}
+	keys, err := next(4 * size)
+	if err != nil {
+		return buf, err
+	}
+	cardinalities := make([]int, size)
+	for i := range cardinalities {
+		cardinalities[i] = int(binary.LittleEndian.Uint16(keys[4*i+2:])) + 1
+	}
+	if runs == nil || size >= noOffsetThreshold {
+		if _, err := next(4 * size); err != nil {
+			return buf, err
+		}
+	}
+	for i, card := range cardinalities {
+		switch {
+		case runs != nil && runs[i/8]&(1<<(i%8)) != 0:
+			b, err := next(2)
+			if err != nil {
+				return buf, err
+			}
+			if _, err := next(4 * int(binary.LittleEndian.Uint16(b))); err != nil {
+				return buf, err
+			}
+		case card > arrayContainerMaxSize:
+			if _, err := next(bitsetContainerSizeInBytes); err != nil {
+				return buf, err
+			}
+		default:
+			if _, err := next(2 * card); err != nil {
+				return buf, err
+			}
+		}
+	}
+	return buf, nil
+}
@@ -0,0 +1,346 @@
+package gocroaring
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/gob"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"runtime"
+	"testing"
+)
+
+func testBitmaps() []*Bitmap {
+	sparse := New()
+	for i := 0; i < 1000; i++ {
+		sparse.Add(uint32(rand.Intn(1 << 30)))
+	}
+	dense := New()
+	for i := 0; i < 100000; i++ {
+		dense.Add(uint32(rand.Intn(1 << 20)))
+	}
+	runs := New()
+	for i := 0; i < 10; i++ {
+		runs.AddRange(uint64(i)<<20, uint64(i)<<20+50000)
+	}
+	runs.Add(3<<30, 3<<30+2)
+	runs.RunOptimize()
+	small := New(1, 2, 3)
+	small.AddRange(1000, 2000)
+	small.RunOptimize()
+	return []*Bitmap{New(), New(0xFFFFFFFF), sparse, dense, runs, small}
+}
+
+func TestAppendTo(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		prefix := []byte("prefix")
+		b := rb.AppendTo(prefix)
+		if !bytes.Equal(b[:len(prefix)], prefix) || len(b) != len(prefix)+rb.SerializedSizeInBytes() {
+			t.Fatal("bad AppendTo")
+		}
+		newrb, err := Read(b[len(prefix):])
+		if err != nil || !rb.Equals(newrb) {
+			t.Error("Bad read", err)
+		}
+	}
+}
+
+func TestWriteToReadFrom(t *testing.T) {
+	bitmaps := testBitmaps()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	for _, rb := range bitmaps {
+		n, err := rb.WriteTo(zw)
+		if err != nil || n != int64(rb.SerializedSizeInBytes()) {
+			t.Fatal("WriteTo failed", n, err)
+		}
+	}
+	zw.Close()
+
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rb := range bitmaps {
+		newrb := New(42)
+		n, err := newrb.ReadFrom(zr)
+		if err != nil || n != int64(rb.SerializedSizeInBytes()) {
+			t.Fatal("ReadFrom failed", n, err)
+		}
+		if !rb.Equals(newrb) {
+			t.Error("Bad read")
+		}
+	}
+	var rb Bitmap
+	if _, err := rb.ReadFrom(zr); err != io.EOF {
+		t.Errorf("expected io.EOF at the end of the stream, got %v", err)
+	}
+
+	whole := bitmaps[3].AppendTo(nil)
+	for _, cut := range []int{1, 5, 10, len(whole) / 2, len(whole) - 1} {
+		if _, err := New().ReadFrom(bytes.NewReader(whole[:cut])); err != io.ErrUnexpectedEOF {
+			t.Errorf("cut at %d: expected io.ErrUnexpectedEOF, got %v", cut, err)
+		}
+	}
+	if _, err := New().ReadFrom(bytes.NewReader([]byte{1, 2, 3, 4, 5, 6, 7, 8})); err == nil {
+		t.Error("expected an error on a bad cookie")
+	}
+}
+
+type withBitmaps struct {
+	Name    string
+	Set     *Bitmap
+	Missing *Bitmap
+}
+
+func TestMarshalBinary(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		data, err := rb.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		newrb := new(Bitmap)
+		if err := newrb.UnmarshalBinary(data); err != nil || !rb.Equals(newrb) {
+			t.Error("Bad read", err)
+		}
+		if err := newrb.UnmarshalBinary(data[:len(data)-1]); err == nil {
+			t.Error("expected an error on truncated input")
+		}
+	}
+	if err := New().UnmarshalBinary(nil); err == nil {
+		t.Error("expected an error on empty input")
+	}
+	var missing *Bitmap
+	data, err := missing.MarshalBinary()
+	if err != nil || !bytes.Equal(data, New().AppendTo(nil)) {
+		t.Error("a nil bitmap should be encoded as an empty bitmap")
+	}
+}
+
+func TestGob(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(withBitmaps{"gob", rb, nil}); err != nil {
+			t.Fatal(err)
+		}
+		var decoded withBitmaps
+		if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatal(err)
+		}
+		if decoded.Name != "gob" || !rb.Equals(decoded.Set) || decoded.Missing != nil {
+			t.Error("Bad read")
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	data, err := json.Marshal(withBitmaps{"json", New(1, 2, 3), nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"Name":"json","Set":[1,2,3],"Missing":null}` {
+		t.Errorf("bad JSON %s", data)
+	}
+	var decoded withBitmaps
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !decoded.Set.Equals(New(1, 2, 3)) || decoded.Missing != nil {
+		t.Error("Bad read")
+	}
+
+	// each field chooses its encoding
+	type withBase64 struct {
+		Array   *Bitmap
+		Base64  *Base64Bitmap
+		Missing *Base64Bitmap
+	}
+	for _, rb := range testBitmaps() {
+		data, err := json.Marshal(withBase64{rb, (*Base64Bitmap)(rb), nil})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var decoded withBase64
+		if err := json.Unmarshal(data, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		if !rb.Equals(decoded.Array) || !rb.Equals(decoded.Base64.Bitmap()) || decoded.Missing != nil {
+			t.Error("Bad read")
+		}
+	}
+	data, _ = json.Marshal((*Base64Bitmap)(New(1, 2, 3)))
+	if string(data) != `"`+base64.StdEncoding.EncodeToString(New(1, 2, 3).AppendTo(nil))+`"` {
+		t.Errorf("bad JSON %s", data)
+	}
+
+	// the encoding methods have pointer receivers, so a field that is not a pointer is encoded as {}
+	values, err := json.Marshal(struct {
+		Set    Bitmap
+		Base64 Base64Bitmap
+	}{})
+	if err != nil || string(values) != `{"Set":{},"Base64":{}}` {
+		t.Errorf("expected values to be encoded as {}, got %s %v", values, err)
+	}
+
+	// both types accept both encodings
+	rb := New(7)
+	if err := json.Unmarshal([]byte(` [ 4, 5, 6 ] `), rb); err != nil || !rb.Equals(New(4, 5, 6)) {
+		t.Error("Bad read", err)
+	}
+	if err := json.Unmarshal(data, rb); err != nil || !rb.Equals(New(1, 2, 3)) {
+		t.Error("Bad read", err)
+	}
+	if err := json.Unmarshal([]byte(`[8]`), (*Base64Bitmap)(rb)); err != nil || !rb.Equals(New(8)) {
+		t.Error("Bad read", err)
+	}
+	if err := json.Unmarshal([]byte(`[]`), rb); err != nil || !rb.IsEmpty() {
+		t.Error("Bad read", err)
+	}
+	for _, bad := range []string{`"not base64"`, `[-1]`, `{}`, `"AAAA"`} {
+		if err := json.Unmarshal([]byte(bad), rb); err == nil {
+			t.Errorf("expected an error for %s", bad)
+		}
+	}
+}
+
+// withBitmapField holds a Bitmap by value, after another field
+type withBitmapField struct {
+	X int
+	B Bitmap
+}
+
+func TestDecodeIntoField(t *testing.T) {
+	rb := New(1, 2, 3, 100000)
+	data := rb.AppendTo(nil)
+	jsonData, err := json.Marshal(rb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoders := map[string]func(v *withBitmapField) error{
+		"UnmarshalBinary": func(v *withBitmapField) error { return v.B.UnmarshalBinary(data) },
+		"GobDecode":       func(v *withBitmapField) error { return v.B.GobDecode(data) },
+		"ReadFrom": func(v *withBitmapField) error {
+			_, err := v.B.ReadFrom(bytes.NewReader(data))
+			return err
+		},
+		"UnmarshalJSON": func(v *withBitmapField) error {
+			return json.Unmarshal([]byte(`{"X":1,"B":`+string(jsonData)+`}`), v)
+		},
+	}
+	for name, decode := range decoders {
+		v := new(withBitmapField)
+		if err := decode(v); err != nil {
+			t.Fatal(name, err)
+		}
+		if !v.B.Equals(rb) {
+			t.Error(name, "bad read")
+		}
+		// decoding again replaces the content
+		if err := decode(v); err != nil || !v.B.Equals(rb) {
+			t.Error(name, "bad read", err)
+		}
+		if name == "UnmarshalBinary" {
+			v.B.Free()
+			expectPanic(t, "Contains", func() { v.B.Contains(1) })
+		}
+	}
+	// the finalizers free the bitmaps of the unreachable structs
+	runtime.GC()
+	runtime.GC()
+}
+
+func TestWriteReadNative(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		buf := make([]byte, rb.NativeSizeInBytes())
+		if err := rb.WriteNative(buf[:len(buf)-1]); err == nil {
+			t.Error("expected an error when the buffer is too small")
+		}
+		if err := rb.WriteNative(buf); err != nil {
+			t.Fatal("WriteNative failed", err)
+		}
+		newrb, err := ReadNative(buf)
+		if err != nil || !rb.Equals(newrb) {
+			t.Error("Bad read", err)
+		}
+	}
+	if _, err := ReadNative(nil); err == nil {
+		t.Error("expected an error on empty input")
+	}
+}
+
+func TestReadAny(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		portable := rb.AppendTo(nil)
+		native := make([]byte, rb.NativeSizeInBytes())
+		rb.WriteNative(native)
+		// frozen input, deliberately misaligned
+		frozen := make([]byte, rb.FrozenSizeInBytes()+1)[1:]
+		rb.WriteFrozen(frozen)
+
+		for name, b := range map[string][]byte{"portable": portable, "native": native, "frozen": frozen} {
+			newrb, err := ReadAny(b)
+			if err != nil {
+				t.Errorf("%s: ReadAny failed %v", name, err)
+				continue
+			}
+			for i := range b {
+				b[i] = 0
+			}
+			if !rb.Equals(newrb) {
+				t.Errorf("%s: Bad read", name)
+			}
+		}
+	}
+	for _, bad := range [][]byte{nil, {1}, {2, 3, 4, 5, 6, 7}, []byte("not a bitmap")} {
+		if _, err := ReadAny(bad); err == nil {
+			t.Errorf("expected an error for %v", bad)
+		}
+	}
+}
+
+func TestReadPortableView(t *testing.T) {
+	for _, rb := range testBitmaps() {
+		buf := rb.AppendTo(nil)
+		view, err := ReadPortableView(buf)
+		if err != nil {
+			t.Fatal("ReadPortableView failed", err)
+		}
+		runtime.GC() // the view must keep the buffer alive
+		if !rb.Equals(view) {
+			t.Error("Bad read")
+		}
+		if _, ok := interface{}(view).(interface{ Add(x ...uint32) }); ok {
+			t.Error("a portable view should not be mutable")
+		}
+		c := view.Clone()
+		c.Add(123456789)
+		if !c.Contains(123456789) || (!rb.Contains(123456789) && view.Contains(123456789)) {
+			t.Error("a clone of a view should be mutable and independent")
+		}
+		view.Free()
+		expectPanic(t, "Contains", func() { view.Contains(1) })
+
+		if _, err := ReadPortableView(buf[:len(buf)-1]); err == nil {
+			t.Error("expected an error on truncated input")
+		}
+	}
+	if _, err := ReadPortableView(nil); err == nil {
+		t.Error("expected an error on empty input")
+	}
+	if _, err := ReadPortableView([]byte("not a bitmap")); err == nil {
+		t.Error("expected an error on bad input")
+	}
+
+	// the view references the buffer rather than a copy of it, when the buffer can be pinned
+	if !canPin {
+		return
+	}
+	buf := New(1, 2, 3).AppendTo(nil)
+	view, _ := ReadPortableView(buf)
+	buf[len(buf)-2] = 4 // the last array value, stored in little endian
+	if !view.Contains(4) || view.Contains(3) {
+		t.Error("expected the view to reflect the buffer")
+	}
+}
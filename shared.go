@@ -0,0 +1,68 @@
+package gocroaring
+
+import (
+	"runtime"
+	"sync/atomic"
+	"unsafe"
+)
+
+// SharedBitmap is a read-only bitmap shared by several owners, for instance goroutines reading
+// from a cache. Each owner calls Retain to get a reference and Release once done: the memory is
+// freed when the last reference is released, and the bitmap must not be used afterwards.
+// Retain and Release are safe for concurrent use, and so are the queries, as long as every
+// caller holds a reference.
+type SharedBitmap struct {
+	readOnlyBitmap
+	refs     atomic.Int32
+	released atomic.Value // where the last reference was released, with the gocroaring_debug build tag
+}
+
+// NewSharedBitmap moves the content of rb into a shared bitmap holding a single reference.
+// rb must not be used afterwards.
+func NewSharedBitmap(rb *Bitmap) *SharedBitmap {
+	rb.check("NewSharedBitmap")
+	rb.disown()
+	answer := &SharedBitmap{}
+	answer.rb.cpointer = rb.cpointer
+	answer.refs.Store(1)
+	rb.cpointer = nil
+	runtime.SetFinalizer(answer, finalizeSharedBitmap)
+	return answer
+}
+
+func finalizeSharedBitmap(sb *SharedBitmap) {
+	free(&sb.rb)
+}
+
+// Retain adds a reference to the bitmap and returns it
+func (sb *SharedBitmap) Retain() *SharedBitmap {
+	if sb.refs.Add(1) <= 1 {
+		sb.overReleased("Retain")
+	}
+	return sb
+}
+
+// Release removes a reference to the bitmap, and frees it if it was the last one.
+// Releasing more references than were retained panics; with the gocroaring_debug
+// build tag, the message tells where the last reference was released.
+func (sb *SharedBitmap) Release() {
+	refs := sb.refs.Add(-1)
+	if refs > 0 {
+		return
+	}
+	if refs < 0 {
+		sb.overReleased("Release")
+	}
+	sb.released.Store(callerStack())
+	runtime.SetFinalizer(sb, nil)
+	untrack(unsafe.Pointer(sb.rb.cpointer), false)
+	free(&sb.rb)
+}
+
+func (sb *SharedBitmap) overReleased(op string) {
+	message := "gocroaring: " + op + " called on a SharedBitmap whose references were all released"
+	if stack, _ := sb.released.Load().(string); stack != "" {
+		message += "\nthe last reference was released by " + stack
+	}
+	panic(message)
+}
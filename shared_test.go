@@ -0,0 +1,35 @@
+package gocroaring
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestSharedBitmap(t *testing.T) {
+	rb := New(1, 2, 3, 1000000)
+	sb := NewSharedBitmap(rb)
+	expectPanic(t, "Contains", func() { rb.Contains(1) })
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		sb.Retain()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer sb.Release()
+			if !sb.Contains(1000000) || sb.Cardinality() != 4 {
+				t.Error("bad shared bitmap")
+			}
+			if And(New(2, 5), sb).Cardinality() != 1 {
+				t.Error("bad And")
+			}
+		}()
+	}
+	wg.Wait()
+	if !sb.Equals(New(1, 2, 3, 1000000)) {
+		t.Error("the bitmap was freed too early")
+	}
+	sb.Release()
+	expectPanic(t, "Cardinality", func() { sb.Cardinality() })
+	expectPanic(t, "Release", sb.Release)
+	expectPanic(t, "Retain", func() { sb.Retain() })
+}
@@ -0,0 +1,58 @@
+//go:build ignore
+
+// gen_bitmap64 writes the bitmap64_*.bin golden files used by TestBitmap64Golden
+// with the pure-Go roaring64 package, so that the tests check gocroaring against
+// an independent implementation of the portable 64-bit format.
+//
+// It is not part of the gocroaring module, run it from a scratch module:
+//
+//	mkdir /tmp/gen && cd /tmp/gen && go mod init gen
+//	cp $GOCROARING/testdata/gen_bitmap64.go .
+//	go get github.com/RoaringBitmap/roaring/v2@v2.29.0
+//	go run gen_bitmap64.go $GOCROARING/testdata
+//
+// The checked-in files were generated with roaring v2.29.0.
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/RoaringBitmap/roaring/v2/roaring64"
+)
+
+func main() {
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: go run gen_bitmap64.go <testdata dir>")
+		os.Exit(2)
+	}
+	dir := os.Args[1]
+
+	// keep in sync with TestBitmap64Golden
+	high := uint64(1) << 63
+	runs := roaring64.New()
+	runs.AddRange(1<<32, 1<<32+1000)
+	runs.AddRange(high|3<<16|10, high|3<<16|15)
+	runs.AddRange(high|3<<16|100, high|3<<16|110)
+	runs.RunOptimize()
+
+	for _, tc := range []struct {
+		file string
+		rb   *roaring64.Bitmap
+	}{
+		{"bitmap64_empty.bin", roaring64.New()},
+		{"bitmap64_array.bin", roaring64.BitmapOf(1, 2, 3, 1<<32+5, 1<<33+7)},
+		{"bitmap64_run.bin", runs},
+	} {
+		data, err := tc.rb.ToBytes()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, tc.file, err)
+			os.Exit(1)
+		}
+		if err := os.WriteFile(filepath.Join(dir, tc.file), data, 0o644); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}